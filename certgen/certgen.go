@@ -0,0 +1,178 @@
+// Command certgen generates a local test certificate authority and a certificate for every node, for mutual TLS between the nodes.
+//
+// Run it with:
+//
+//	go run . -out ../certs -nodes node0,node1,node2
+//
+// It writes ca.pem and ca-key.pem, and <name>.pem and <name>-key.pem for every node, whose common name is the node's name.
+// A certificate authority already in the output directory is reused, so certificates for new nodes can be added later.
+// The certificates are for testing only.
+package main
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+func main() {
+	var out = flag.String("out", "certs", "The directory to write the certificates to.")
+	var nodes = flag.String("nodes", "node0,node1,node2", "The names of the nodes to generate certificates for.")
+	var hosts = flag.String("hosts", "127.0.0.1,::1,localhost", "The ip addresses and host names the nodes run on.")
+	var days = flag.Int("days", 365, "The number of days the certificates are valid.")
+	flag.Parse()
+
+	if err := os.MkdirAll(*out, 0755); err != nil {
+		log.Fatalf("Could not create %v. :: %v", *out, err)
+	}
+
+	validity := time.Duration(*days) * 24 * time.Hour
+	ca, caKey, err := loadCA(*out)
+	if errors.Is(err, os.ErrNotExist) {
+		ca, caKey, err = createCA(*out, validity)
+	}
+	if err != nil {
+		log.Fatalf("Could not create the certificate authority. :: %v", err)
+	}
+
+	for _, name := range strings.Split(*nodes, ",") {
+		if name == "" {
+			continue
+		}
+		if err := createNode(*out, name, strings.Split(*hosts, ","), validity, ca, caKey); err != nil {
+			log.Fatalf("Could not create the certificate of %v. :: %v", name, err)
+		}
+		fmt.Printf("Wrote the certificate of %v to %v.\n", name, filepath.Join(*out, name+".pem"))
+	}
+}
+
+// loadCA loads the certificate authority from the directory dir.
+func loadCA(dir string) (*x509.Certificate, crypto.Signer, error) {
+	certPEM, err := os.ReadFile(filepath.Join(dir, "ca.pem"))
+	if err != nil {
+		return nil, nil, err
+	}
+	keyPEM, err := os.ReadFile(filepath.Join(dir, "ca-key.pem"))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	certBlock, _ := pem.Decode(certPEM)
+	keyBlock, _ := pem.Decode(keyPEM)
+	if certBlock == nil || keyBlock == nil {
+		return nil, nil, fmt.Errorf("invalid certificate authority in %v", dir)
+	}
+
+	cert, err := x509.ParseCertificate(certBlock.Bytes)
+	if err != nil {
+		return nil, nil, err
+	}
+	key, err := x509.ParseECPrivateKey(keyBlock.Bytes)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	fmt.Printf("Using the certificate authority in %v.\n", dir)
+	return cert, key, nil
+}
+
+// createCA creates a self-signed certificate authority valid for validity and writes it to the directory dir.
+func createCA(dir string, validity time.Duration) (*x509.Certificate, crypto.Signer, error) {
+	template := &x509.Certificate{
+		Subject:               pkix.Name{CommonName: "mandatory-exercise-2 test CA"},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+	}
+
+	key, der, err := sign(template, nil, nil, validity)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := write(dir, "ca", der, key); err != nil {
+		return nil, nil, err
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	fmt.Printf("Wrote the certificate authority to %v.\n", filepath.Join(dir, "ca.pem"))
+	return cert, key, nil
+}
+
+// createNode creates the certificate of the node name for the given hosts, signed by the certificate authority,
+// and writes it to the directory dir. The certificate serves both as a server and as a client certificate.
+func createNode(dir string, name string, hosts []string, validity time.Duration, ca *x509.Certificate, caKey crypto.Signer) error {
+	template := &x509.Certificate{
+		Subject:     pkix.Name{CommonName: name},
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else if host != "" {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+
+	key, der, err := sign(template, ca, caKey, validity)
+	if err != nil {
+		return err
+	}
+	return write(dir, name, der, key)
+}
+
+// sign generates a key for the template and signs it with the certificate authority ca, or by itself if ca is nil.
+// It returns the key and the DER encoded certificate.
+func sign(template *x509.Certificate, ca *x509.Certificate, caKey crypto.Signer, validity time.Duration) (*ecdsa.PrivateKey, []byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, nil, err
+	}
+	template.SerialNumber = serial
+	template.NotBefore = time.Now().Add(-time.Hour)
+	template.NotAfter = time.Now().Add(validity)
+
+	if ca == nil {
+		ca, caKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca, key.Public(), caKey)
+	return key, der, err
+}
+
+// write writes the DER encoded certificate and its key to <name>.pem and <name>-key.pem in the directory dir.
+func write(dir string, name string, der []byte, key *ecdsa.PrivateKey) error {
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return err
+	}
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	if err := os.WriteFile(filepath.Join(dir, name+".pem"), certPEM, 0644); err != nil {
+		return err
+	}
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	return os.WriteFile(filepath.Join(dir, name+"-key.pem"), keyPEM, 0600)
+}
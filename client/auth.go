@@ -0,0 +1,52 @@
+package client
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+	"mandatory-exercise-2/utils"
+	"strconv"
+	"time"
+)
+
+// WithSecret returns a dial option which signs every RPC with the secret of the cluster, for the address it is sent to,
+// so a server started with server.WithSecret at that address accepts it.
+func WithSecret(secret []byte) grpc.DialOption {
+	return grpc.WithChainUnaryInterceptor(func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		ctx, err := sign(ctx, secret, method, cc.Target(), req)
+		if err != nil {
+			return err
+		}
+		return invoker(ctx, method, req, reply, cc, opts...)
+	})
+}
+
+// sign adds the timestamp, a fresh nonce and the signature of the RPC to method of the node at the address destination
+// with the request req to the outgoing metadata of ctx.
+func sign(ctx context.Context, secret []byte, method string, destination string, req interface{}) (context.Context, error) {
+	m, ok := req.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("cannot sign a request of type %T", req)
+	}
+	request, err := proto.MarshalOptions{Deterministic: true}.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+
+	random := make([]byte, 16)
+	if _, err := rand.Read(random); err != nil {
+		return nil, err
+	}
+	nonce := hex.EncodeToString(random)
+	timestamp := strconv.FormatInt(time.Now().UnixNano(), 10)
+
+	return metadata.AppendToOutgoingContext(ctx,
+		utils.AUTH_TIMESTAMP, timestamp,
+		utils.AUTH_NONCE, nonce,
+		utils.AUTH_SIGNATURE, utils.Sign(secret, method, destination, timestamp, nonce, request),
+	), nil
+}
@@ -1,16 +1,19 @@
 package client
 
 import (
+	"context"
 	"google.golang.org/grpc"
 	"mandatory-exercise-2/service"
 	"mandatory-exercise-2/utils"
 )
 
-func NewClient(ipAddress string, logger *utils.Logger) service.ServiceClient {
+// NewClient connects to the peer at the given ip address, blocking until the peer is up.
+// The connection is made with the given options, which default to an insecure connection.
+func NewClient(ipAddress string, logger *utils.Logger, opts ...grpc.DialOption) service.ServiceClient {
 	logger.InfoPrintf("Trying to connect to peer at %v.\n", ipAddress)
 
 	// Create client connection to a server
-	conn, err := grpc.Dial(ipAddress, grpc.WithInsecure(), grpc.WithBlock())
+	conn, err := grpc.Dial(ipAddress, dialOptions(opts)...)
 	if err != nil {
 		defer conn.Close()
 		logger.ErrorFatalf("Could not connect to peer at %v. :: %v", ipAddress, err)
@@ -20,3 +23,25 @@ func NewClient(ipAddress string, logger *utils.Logger) service.ServiceClient {
 
 	return service.NewServiceClient(conn)
 }
+
+// Dial connects to a peer like NewClient, but gives up when ctx is done instead of blocking until the peer is up.
+func Dial(ctx context.Context, ipAddress string, logger *utils.Logger, opts ...grpc.DialOption) (service.ServiceClient, error) {
+	logger.InfoPrintf("Trying to connect to peer at %v.\n", ipAddress)
+
+	conn, err := grpc.DialContext(ctx, ipAddress, dialOptions(opts)...)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.InfoPrintf("Successfully connected to peer at %v.\n", ipAddress)
+
+	return service.NewServiceClient(conn), nil
+}
+
+// dialOptions returns the options opts, or an insecure connection if there are none, for a blocking dial.
+func dialOptions(opts []grpc.DialOption) []grpc.DialOption {
+	if len(opts) == 0 {
+		opts = []grpc.DialOption{grpc.WithInsecure()}
+	}
+	return append(opts, grpc.WithBlock())
+}
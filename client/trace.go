@@ -0,0 +1,25 @@
+package client
+
+import (
+	"context"
+	"go.opentelemetry.io/otel/propagation"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"mandatory-exercise-2/utils"
+)
+
+// WithTracing returns a dial option which sends the trace context of every RPC to the server in its metadata,
+// in the W3C Trace Context format, so a server started with server.WithTracing continues the trace of the caller.
+func WithTracing() grpc.DialOption {
+	return grpc.WithChainUnaryInterceptor(func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		md, ok := metadata.FromOutgoingContext(ctx)
+		if ok {
+			md = md.Copy()
+		} else {
+			md = metadata.MD{}
+		}
+
+		propagation.TraceContext{}.Inject(ctx, utils.MetadataCarrier(md))
+		return invoker(metadata.NewOutgoingContext(ctx, md), method, req, reply, cc, opts...)
+	})
+}
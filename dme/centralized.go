@@ -0,0 +1,338 @@
+package dme
+
+import (
+	"context"
+	"mandatory-exercise-2/service"
+	"mandatory-exercise-2/utils"
+	"sync"
+)
+
+// centralized is a centralized lock server.
+// One node, the coordinator, grants the lock to one node at a time and queues all other requests in FIFO order.
+// An entry costs 3 messages: a request, a grant and a release.
+// A node giving up its request withdraws it with Cancel, which the coordinator remembers in case the request arrives later.
+type centralized struct {
+	*instance
+	coordinator string     // coordinator is the name of the coordinator. If empty, the first of all members is the coordinator.
+	mu          sync.Mutex // mu guards all the fields below.
+
+	// The node as a requester.
+	state    int           // The current state of the node.
+	sequence int32         // sequence is the request number of the node's outstanding request.
+	granted  chan struct{} // granted is closed when the outstanding request is granted.
+
+	// The node as the coordinator.
+	holder         string           // holder is the node holding the lock, or empty if it is free.
+	holderSequence int32            // holderSequence is the request number of the holder's request.
+	queue          *utils.Queue     // queue is the nodes waiting for the lock, with their request numbers.
+	cancelled      map[string]int32 // cancelled is the request number of the latest request each node has withdrawn.
+	outboxes       map[string]*outbox
+	once           sync.Once
+}
+
+// lock asks the coordinator for the lock and blocks until it is granted, or until ctx is done.
+// If ctx is done first, the request is withdrawn from the coordinator.
+func (c *centralized) lock(ctx context.Context) error {
+	granted, sequence, err := c.ask(ctx)
+	if err == nil {
+		select {
+		case <-granted:
+			return nil
+		case <-ctx.Done():
+			err = ctx.Err()
+		}
+	}
+
+	c.mu.Lock()
+	held := c.state == HELD
+	c.mu.Unlock()
+	if held {
+		return nil
+	}
+
+	c.withdraw(sequence)
+	<-c.local
+	return err
+}
+
+// tryLock asks the coordinator for the lock and withdraws the request if it is not granted straight away.
+func (c *centralized) tryLock(ctx context.Context) bool {
+	granted, sequence, err := c.ask(ctx)
+	if err == nil {
+		select {
+		case <-granted:
+			return true
+		default:
+		}
+	}
+
+	c.logger.InfoPrintf("%v could not get the lock straight away.\n", c.name)
+	c.withdraw(sequence)
+	<-c.local
+	return false
+}
+
+// unlock leaves HELD and releases the lock at the coordinator.
+func (c *centralized) unlock() {
+	c.mu.Lock()
+	state, sequence := c.state, c.sequence
+	c.mu.Unlock()
+
+	if state != HELD {
+		c.logger.WarningPrintf("%v tried to unlock without holding the lock.\n", c.name)
+		return
+	}
+
+	c.release(sequence)
+	<-c.local
+}
+
+// setup picks the first of all members as the coordinator, unless one has been configured.
+func (c *centralized) setup() {
+	<-c.ready
+	c.once.Do(func() {
+		if c.coordinator == "" {
+			c.coordinator = c.members()[0]
+		}
+		c.logger.InfoPrintf("%v uses the coordinator %v.\n", c.name, c.coordinator)
+	})
+}
+
+// ask makes the node enter WANTED and sends a numbered request to the coordinator.
+// It returns a channel which is closed when the request is granted, and the request number.
+func (c *centralized) ask(ctx context.Context) (<-chan struct{}, int32, error) {
+	c.setup()
+	c.mu.Lock()
+	c.state = WANTED
+	c.sequence++
+	c.granted = make(chan struct{})
+	granted := c.granted
+	r := &service.Request{Name: c.name, Sequence: c.sequence, Resource: c.resource}
+	c.logger.InfoPrintf("%v entered WANTED\n", c.name)
+
+	if c.isCoordinator() {
+		if c.request(r) {
+			c.held()
+		}
+		c.mu.Unlock()
+		return granted, r.Sequence, nil
+	}
+	c.mu.Unlock()
+
+	c.logger.InfoPrintf("(%v, Send) %v is requesting the lock from %v.\n", r.Sequence, c.name, c.coordinator)
+	reply, err := c.peers.get(c.coordinator).Publish(ctx, r)
+	if err != nil {
+		c.logger.ErrorPrintf("Error sending request to %v. :: %v\n", c.coordinator, err)
+		return granted, r.Sequence, err
+	}
+
+	if reply.Ack {
+		c.mu.Lock()
+		c.held()
+		c.mu.Unlock()
+	}
+
+	return granted, r.Sequence, nil
+}
+
+// isCoordinator reports whether the node is the coordinator.
+func (c *centralized) isCoordinator() bool {
+	return c.coordinator == c.name
+}
+
+// held makes the node enter HELD. The mutex must be held.
+func (c *centralized) held() {
+	c.state = HELD
+	c.logger.InfoPrintf("%v entered HELD\n", c.name)
+	c.logger.InfoPrintf("%v IS NOW IN THE CRITICAL SECTION!\n", c.name)
+	close(c.granted)
+}
+
+// release makes the node enter RELEASED and releases the lock granted to its request with the given number at the coordinator.
+func (c *centralized) release(sequence int32) {
+	c.mu.Lock()
+	c.state = RELEASED
+	c.logger.InfoPrintf("%v entered RELEASED\n", c.name)
+	r := &service.Request{Name: c.name, Sequence: sequence, Resource: c.resource}
+
+	if c.isCoordinator() {
+		c.free(r)
+		c.mu.Unlock()
+		return
+	}
+	c.mu.Unlock()
+
+	if _, err := c.peers.get(c.coordinator).Release(context.Background(), r); err != nil {
+		c.logger.ErrorPrintf("Error releasing the lock at %v. :: %v\n", c.coordinator, err)
+	}
+}
+
+// withdraw makes the node enter RELEASED and withdraws its request with the given number at the coordinator.
+func (c *centralized) withdraw(sequence int32) {
+	c.mu.Lock()
+	c.state = RELEASED
+	c.logger.InfoPrintf("(%v) %v withdrew its request and entered RELEASED\n", sequence, c.name)
+	r := &service.Request{Name: c.name, Sequence: sequence, Resource: c.resource}
+
+	if c.isCoordinator() {
+		c.cancel(r)
+		c.mu.Unlock()
+		return
+	}
+	c.mu.Unlock()
+
+	if _, err := c.peers.get(c.coordinator).Cancel(context.Background(), r); err != nil {
+		c.logger.ErrorPrintf("Error withdrawing the request at %v. :: %v\n", c.coordinator, err)
+	}
+}
+
+// request grants the lock to r if it is free, or queues r otherwise. It reports whether the lock was granted.
+// A request which has already been withdrawn is ignored. The mutex must be held.
+func (c *centralized) request(r *service.Request) bool {
+	if r.Sequence <= c.cancelled[r.Name] {
+		c.logger.InfoPrintf("(%v) %v ignored the withdrawn request of %v.\n", r.Sequence, c.name, r.Name)
+		return false
+	}
+
+	if c.holder == "" {
+		c.holder, c.holderSequence = r.Name, r.Sequence
+		c.logger.InfoPrintf("%v granted the lock to %v.\n", c.name, r.Name)
+		return true
+	}
+
+	c.queue.Enqueue(r.Sequence, r.Name)
+	c.logger.InfoPrintf("%v is enqueued %v\n", c.name, r.Name)
+	return false
+}
+
+// free releases the lock held by r and grants it to the first node in the queue.
+// If r does not hold the lock, it is withdrawn from the queue. The mutex must be held.
+func (c *centralized) free(r *service.Request) {
+	if c.holder != r.Name || c.holderSequence != r.Sequence {
+		c.queue.Remove(r.Name)
+		return
+	}
+
+	c.holder = ""
+	if c.queue.IsEmpty() {
+		return
+	}
+
+	sequence, name := c.queue.Dequeue()
+	c.holder, c.holderSequence = name, sequence
+	c.logger.InfoPrintf("%v dequeued %v and granted it the lock.\n", c.name, name)
+
+	if name == c.name {
+		c.held()
+		return
+	}
+
+	o, ok := c.outboxes[name]
+	if !ok {
+		o = newOutbox()
+		c.outboxes[name] = o
+	}
+
+	grant := &service.Request{Name: c.name, Sequence: sequence, Resource: c.resource}
+	o.post(func() {
+		if _, err := c.peers.get(name).ReplySender(context.Background(), grant); err != nil {
+			c.logger.ErrorPrintf("Could not grant the lock to %v. :: %v\n", name, err)
+		}
+	})
+}
+
+// Publish receives a request for the lock at the coordinator. It is acknowledged if the lock is granted straight away.
+func (c *centralized) Publish(_ context.Context, r *service.Request) (*service.Reply, error) {
+	c.setup()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.logger.InfoPrintf("(%v, Receive) %v received request from %v.\n", r.Sequence, c.name, r.Name)
+
+	return &service.Reply{Ack: c.request(r)}, nil
+}
+
+// ReplySender receives a grant of the lock from the coordinator.
+// A grant of a withdrawn request is ignored, as the withdrawal releases it at the coordinator.
+func (c *centralized) ReplySender(_ context.Context, r *service.Request) (*service.Reply, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.logger.InfoPrintf("(%v, Receive) %v was granted the lock by %v.\n", r.Sequence, c.name, r.Name)
+
+	if c.state == WANTED && r.Sequence == c.sequence {
+		c.held()
+	}
+
+	return &service.Reply{}, nil
+}
+
+// cancel withdraws the request r, releasing the lock if it has been granted to r already, and remembers it as withdrawn.
+// The mutex must be held.
+func (c *centralized) cancel(r *service.Request) {
+	if r.Sequence > c.cancelled[r.Name] {
+		c.cancelled[r.Name] = r.Sequence
+	}
+	c.free(r)
+}
+
+// Cancel receives the withdrawal of a request at the coordinator.
+func (c *centralized) Cancel(_ context.Context, r *service.Request) (*service.Reply, error) {
+	c.setup()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.logger.InfoPrintf("(%v, Receive) %v received withdrawal from %v.\n", r.Sequence, c.name, r.Name)
+
+	c.cancel(r)
+	return &service.Reply{}, nil
+}
+
+// Release receives a release of the lock at the coordinator.
+func (c *centralized) Release(_ context.Context, r *service.Request) (*service.Reply, error) {
+	c.setup()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.logger.InfoPrintf("(%v, Receive) %v received release from %v.\n", r.Sequence, c.name, r.Name)
+
+	c.free(r)
+	return &service.Reply{}, nil
+}
+
+// waiting returns the holder and the queue of the coordinator, which the node asks with GetQueue unless it is the coordinator.
+func (c *centralized) waiting(ctx context.Context) (string, []string, error) {
+	c.setup()
+	if c.isCoordinator() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		return c.holder, c.queue.Names(), nil
+	}
+
+	reply, err := c.peers.get(c.coordinator).GetQueue(ctx, &service.QueueRequest{Name: c.name, Resource: c.resource})
+	if err != nil {
+		c.logger.ErrorPrintf("Could not get the queue of %v. :: %v\n", c.coordinator, err)
+		return "", nil, err
+	}
+
+	return reply.Holder, reply.Queue, nil
+}
+
+// GetQueue returns the holder and the queue of the coordinator.
+func (c *centralized) GetQueue(_ context.Context, q *service.QueueRequest) (*service.QueueReply, error) {
+	c.setup()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.logger.InfoPrintf("%v is requesting the queue of %v.\n", q.Name, c.name)
+
+	return &service.QueueReply{Holder: c.holder, Queue: c.queue.Names()}, nil
+}
+
+// newCentralized creates the centralized algorithm for the instance i with the given coordinator.
+func newCentralized(i *instance, coordinator string) *centralized {
+	return &centralized{
+		instance:    i,
+		coordinator: coordinator,
+		state:       RELEASED,
+		queue:       utils.NewQueue(),
+		cancelled:   make(map[string]int32),
+		outboxes:    make(map[string]*outbox),
+	}
+}
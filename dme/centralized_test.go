@@ -0,0 +1,49 @@
+package dme
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// TestQueue checks that every node sees the holder and the wait queue of the coordinator,
+// and that algorithms without a wait queue return ErrNoQueue.
+func TestQueue(t *testing.T) {
+	ms := newCluster(t, 3, Config{Algorithm: CENTRALIZED})
+	if err := ms[1].Lock(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	locked := make(chan error, 1)
+	go func() { locked <- ms[2].Lock(context.Background()) }()
+
+	for _, m := range ms {
+		var holder string
+		var queue []string
+		for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+			var err error
+			if holder, queue, err = m.Queue(context.Background()); err != nil {
+				t.Fatalf("%v could not get the queue: %v", m.Name(), err)
+			}
+			if len(queue) > 0 {
+				break
+			}
+		}
+		if holder != "d1" || !reflect.DeepEqual(queue, []string{"d2"}) {
+			t.Errorf("%v sees the holder %q and the queue %v, want d1 and [d2]", m.Name(), holder, queue)
+		}
+	}
+
+	ms[1].Unlock()
+	if err := <-locked; err != nil {
+		t.Fatal(err)
+	}
+	ms[2].Unlock()
+
+	ms = newCluster(t, 2, Config{})
+	if _, _, err := ms[0].Queue(context.Background()); !errors.Is(err, ErrNoQueue) {
+		t.Errorf("Queue() = %v with %v, want ErrNoQueue", err, RICART_AGRAWALA)
+	}
+}
@@ -0,0 +1,157 @@
+package dme
+
+import (
+	"context"
+	"mandatory-exercise-2/service"
+	"sync"
+	"time"
+)
+
+// Constants which represent the health of a peer, as seen by the failure detector.
+const (
+	ALIVE     int = 0
+	SUSPECTED     = 1
+	DEAD          = 2
+)
+
+// A FailureDetector describes how a node detects crashed peers.
+// Every Interval, the node sends a heartbeat to each peer. A peer which has not answered a heartbeat for Suspect is SUSPECTED,
+// and a peer which has not answered one for Dead is DEAD, until it answers again.
+type FailureDetector struct {
+	Interval time.Duration // Interval is the interval between heartbeats. 0 disables the failure detector.
+	Suspect  time.Duration // Suspect is the silence after which a peer is SUSPECTED. Defaults to 3 intervals.
+	Dead     time.Duration // Dead is the silence after which a peer is DEAD. Defaults to 10 intervals.
+}
+
+// A detector is the failure detector of a node.
+type detector struct {
+	FailureDetector
+	mu     sync.Mutex                    // mu guards seen and health.
+	seen   map[string]time.Time          // seen is the last time each peer was heard from.
+	health map[string]int                // health is the health of each peer. Peers not in it are ALIVE.
+	watch  func(name string, health int) // watch is called whenever the health of a peer changes.
+	done   chan struct{}                 // done is closed when the node stops.
+}
+
+// newDetector creates a failure detector with the given heartbeats.
+func newDetector(fd FailureDetector) *detector {
+	if fd.Suspect == 0 {
+		fd.Suspect = 3 * fd.Interval
+	}
+	if fd.Dead == 0 {
+		fd.Dead = 10 * fd.Interval
+	}
+
+	return &detector{
+		FailureDetector: fd,
+		seen:            make(map[string]time.Time),
+		health:          make(map[string]int),
+		watch:           func(string, int) {},
+		done:            make(chan struct{}),
+	}
+}
+
+// enabled reports whether the failure detector is running.
+func (d *detector) enabled() bool {
+	return d.Interval > 0
+}
+
+// heard records that the peer name has been heard from.
+func (d *detector) heard(name string) {
+	d.mu.Lock()
+	d.seen[name] = time.Now()
+	d.mu.Unlock()
+}
+
+// forget stops judging the peer name, which has left the cluster.
+func (d *detector) forget(name string) {
+	d.mu.Lock()
+	delete(d.seen, name)
+	delete(d.health, name)
+	d.mu.Unlock()
+}
+
+// healthOf returns the health of the peer name.
+func (d *detector) healthOf(name string) int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.health[name]
+}
+
+// detect sends a heartbeat to every peer each interval and judges the health of the peers, until the node stops.
+func (n *node) detect() {
+	for _, name := range n.peers.names() {
+		n.detector.heard(name)
+	}
+
+	ticker := time.NewTicker(n.detector.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-n.detector.done:
+			return
+		}
+
+		for _, name := range n.peers.names() {
+			go n.heartbeat(name, n.peers.get(name))
+		}
+		n.judge()
+	}
+}
+
+// heartbeat sends a heartbeat to the peer name, and records it as heard from if it answers within an interval.
+func (n *node) heartbeat(name string, peer service.ServiceClient) {
+	ctx, cancel := context.WithTimeout(context.Background(), n.detector.Interval)
+	defer cancel()
+
+	if _, err := peer.Heartbeat(ctx, &service.Request{Name: n.name}); err != nil {
+		return
+	}
+	n.detector.heard(name)
+}
+
+// judge updates the health of every peer from the time it was last heard from, and reports every change.
+func (n *node) judge() {
+	d := n.detector
+	changed := make(map[string]int)
+
+	d.mu.Lock()
+	now := time.Now()
+	for name, seen := range d.seen {
+		health := ALIVE
+		if silence := now.Sub(seen); silence >= d.Dead {
+			health = DEAD
+		} else if silence >= d.Suspect {
+			health = SUSPECTED
+		}
+
+		if health != d.health[name] {
+			d.health[name] = health
+			changed[name] = health
+		}
+	}
+	d.mu.Unlock()
+
+	for name, health := range changed {
+		switch health {
+		case ALIVE:
+			n.logger.WarningPrintf("%v is ALIVE again.\n", name)
+		case SUSPECTED:
+			n.logger.WarningPrintf("%v has not answered for %v. %v SUSPECTS it has crashed.\n", name, d.Suspect, n.name)
+		case DEAD:
+			n.logger.WarningPrintf("%v has not answered for %v. %v declared it DEAD.\n", name, d.Dead, n.name)
+		}
+		d.watch(name, health)
+	}
+}
+
+// Heartbeat receives a heartbeat from a peer, which is thereby heard from.
+func (n *node) Heartbeat(_ context.Context, r *service.Request) (*service.Reply, error) {
+	if n.detector.enabled() {
+		n.detector.heard(r.Name)
+	}
+
+	return &service.Reply{Name: n.name}, nil
+}
@@ -0,0 +1,38 @@
+package dme
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// TestRequestResentToRestartedPeer checks that a request a peer missed while it restarted is sent again,
+// although the peer restarted within the Suspect window and so was never declared DEAD.
+func TestRequestResentToRestartedPeer(t *testing.T) {
+	config := Config{Detector: FailureDetector{Interval: time.Second}}
+	ms := newCluster(t, 2, config)
+	port := ms[1].node.ipAddress.Port
+	ms[1].Stop()
+
+	locked := make(chan error, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		locked <- ms[0].Lock(ctx)
+	}()
+
+	time.Sleep(300 * time.Millisecond)
+	ms[1] = newTestMutex(t, config, "d1", port)
+	ms[1].Start([]string{fmt.Sprint(ms[0].node.ipAddress.Port)})
+
+	select {
+	case err := <-locked:
+		if err != nil {
+			t.Fatalf("d0 could not lock after d1 restarted: %v (health of d1: %v)", err, ms[0].Health("d1"))
+		}
+		ms[0].Unlock()
+	case <-time.After(5 * time.Second):
+		t.Fatalf("d0 still waits for d1 5s after d1 restarted (health of d1: %v)", ms[0].Health("d1"))
+	}
+}
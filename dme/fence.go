@@ -0,0 +1,76 @@
+package dme
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrNoFence is returned by Fence when the algorithm of the cluster does not produce fences. Only RICART_AGRAWALA produces them.
+var ErrNoFence = errors.New("dme: the algorithm does not produce fencing tokens")
+
+// ErrStaleFence is returned by FenceChecker.Check for a fence which is not greater than every fence checked before.
+var ErrStaleFence = errors.New("dme: stale fencing token")
+
+// A Fence is a fencing token, produced by every entry into the critical section.
+// The fences of the cluster's exclusive (WRITE) entries into a resource are strictly increasing,
+// so a downstream system, such as a storage service, can refuse the writes of a node which has lost the lock,
+// e.g. because its lease expired, by refusing every fence lower than the highest one it has seen.
+//
+// A Fence is the Lamport time of the entry in the low 32 bits and the epoch of the cluster in the high 32 bits,
+// so the fences of a cluster restarted with a higher epoch are greater than all fences before the restart.
+// The zero Fence is never produced by an entry.
+type Fence uint64
+
+// newFence creates the Fence of the Lamport time lamport in the given epoch.
+func newFence(epoch uint32, lamport int32) Fence {
+	return Fence(uint64(epoch)<<32 | uint64(uint32(lamport)))
+}
+
+// Epoch returns the epoch of the Fence.
+func (f Fence) Epoch() uint32 {
+	return uint32(f >> 32)
+}
+
+// Lamport returns the Lamport time of the Fence.
+func (f Fence) Lamport() int32 {
+	return int32(uint32(f))
+}
+
+// String returns the Fence as "epoch.lamport".
+func (f Fence) String() string {
+	return fmt.Sprintf("%v.%v", f.Epoch(), f.Lamport())
+}
+
+// A fencer is an algorithm which produces a Fence on every entry into the critical section.
+type fencer interface {
+	fence() Fence // fence returns the Fence of the node's latest entry into the critical section.
+}
+
+// A FenceChecker verifies that fences are strictly increasing, as a downstream system protected by a Resource would.
+// The zero value is ready to use, and a FenceChecker is safe for concurrent use.
+type FenceChecker struct {
+	mu   sync.Mutex // mu guards last.
+	last Fence      // last is the highest fence checked so far.
+}
+
+// Check accepts f if it is greater than every fence checked before, and returns ErrStaleFence otherwise.
+func (c *FenceChecker) Check(f Fence) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if f <= c.last {
+		return fmt.Errorf("%w: %v is not greater than %v", ErrStaleFence, f, c.last)
+	}
+
+	c.last = f
+	return nil
+}
+
+// Last returns the highest fence checked so far.
+func (c *FenceChecker) Last() Fence {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.last
+}
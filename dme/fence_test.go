@@ -0,0 +1,70 @@
+package dme
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// TestFenceCheckerCheck checks that a FenceChecker accepts only fences greater than every fence before,
+// and that a higher epoch outranks any Lamport time.
+func TestFenceCheckerCheck(t *testing.T) {
+	var c FenceChecker
+
+	for _, check := range []struct {
+		fence Fence
+		stale bool
+	}{
+		{0, true},
+		{newFence(0, 1), false},
+		{newFence(0, 5), false},
+		{newFence(0, 5), true},
+		{newFence(0, 3), true},
+		{newFence(1, 2), false},
+		{newFence(0, 1000), true},
+		{newFence(1, 3), false},
+	} {
+		err := c.Check(check.fence)
+		if stale := errors.Is(err, ErrStaleFence); stale != check.stale || (err != nil && !stale) {
+			t.Errorf("Check(%v) = %v, want a stale fence: %v", check.fence, err, check.stale)
+		}
+	}
+
+	if last := c.Last(); last != newFence(1, 3) {
+		t.Errorf("Last() = %v, want %v", last, newFence(1, 3))
+	}
+}
+
+// TestFence checks that every entry of ricart-agrawala produces a fence the FenceChecker accepts,
+// and that other algorithms return ErrNoFence instead of a fence.
+func TestFence(t *testing.T) {
+	ms := newCluster(t, 2, Config{Epoch: 7})
+	var c FenceChecker
+	for round := 0; round < 4; round++ {
+		m := ms[round%2]
+		if err := m.Lock(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		fence, err := m.Fence()
+		m.Unlock()
+
+		if err != nil {
+			t.Fatalf("%v has no fence: %v", m.Name(), err)
+		}
+		if fence.Epoch() != 7 {
+			t.Errorf("the fence %v of %v is not in epoch 7", fence, m.Name())
+		}
+		if err := c.Check(fence); err != nil {
+			t.Errorf("the fence of %v in round %v was refused: %v", m.Name(), round, err)
+		}
+	}
+
+	ms = newCluster(t, 2, Config{Algorithm: CENTRALIZED})
+	if err := ms[0].Lock(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer ms[0].Unlock()
+	if fence, err := ms[0].Fence(); !errors.Is(err, ErrNoFence) {
+		t.Errorf("Fence() = %v, %v with %v, want ErrNoFence", fence, err, CENTRALIZED)
+	}
+}
@@ -0,0 +1,355 @@
+package dme
+
+import (
+	"context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"mandatory-exercise-2/service"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// A Gossip describes the SWIM-style gossip with which a node discovers the members of the cluster and detects failed ones.
+// Every Interval, the node probes a random peer with Ping, sending its membership table along, and merges the table of the answer.
+// If the peer does not answer within half an interval, the node asks up to Probes other peers to probe it with PingReq.
+// If none of them gets an answer either, the peer is SUSPECTED, which the peer refutes by raising its incarnation.
+// A peer SUSPECTED for Suspect is DEAD, and is removed from the peers as if it had left.
+// A live member learnt from gossip which is not a peer yet is joined, so both nodes add each other to their peers.
+type Gossip struct {
+	Interval time.Duration // Interval is the interval between probes. 0 disables gossip.
+	Suspect  time.Duration // Suspect is the time a peer is SUSPECTED before it is DEAD. Defaults to 5 intervals.
+	Probes   int           // Probes is the number of peers asked to probe a peer which did not answer. Defaults to 3.
+}
+
+// A gossiper is the gossip membership of a node.
+type gossiper struct {
+	Gossip
+	mu          sync.Mutex                // mu guards all the fields below.
+	incarnation uint32                    // incarnation is the incarnation of the node itself.
+	rumors      map[string]*service.Rumor // rumors is the latest rumor about every other member. Rumors are never changed, only replaced.
+	suspected   map[string]time.Time      // suspected is the time each SUSPECTED member was first suspected.
+	introducing map[string]bool           // introducing is the members the node is joining.
+	left        bool                      // left is set when the node leaves the cluster, after which it no longer refutes rumors.
+	done        chan struct{}             // done is closed when the node stops.
+}
+
+// newGossiper creates the gossip membership of a node.
+func newGossiper(g Gossip) *gossiper {
+	if g.Suspect == 0 {
+		g.Suspect = 5 * g.Interval
+	}
+	if g.Probes == 0 {
+		g.Probes = 3
+	}
+
+	return &gossiper{
+		Gossip:      g,
+		rumors:      make(map[string]*service.Rumor),
+		suspected:   make(map[string]time.Time),
+		introducing: make(map[string]bool),
+		done:        make(chan struct{}),
+	}
+}
+
+// enabled reports whether the node gossips.
+func (g *gossiper) enabled() bool {
+	return g.Interval > 0
+}
+
+// alive records that the member name at the given address has joined the cluster.
+// The rumor outranks every rumor about an earlier incarnation of the member.
+func (g *gossiper) alive(name string, address string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	incarnation := uint32(0)
+	if r, ok := g.rumors[name]; ok {
+		incarnation = r.Incarnation + 1
+	}
+	g.rumors[name] = &service.Rumor{Name: name, Address: address, Health: int32(ALIVE), Incarnation: incarnation}
+	delete(g.suspected, name)
+}
+
+// dead records that the member name has left the cluster.
+func (g *gossiper) dead(name string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if r, ok := g.rumors[name]; ok {
+		g.rumors[name] = &service.Rumor{Name: name, Address: r.Address, Health: int32(DEAD), Incarnation: r.Incarnation}
+	}
+	delete(g.suspected, name)
+}
+
+// leave records that the node leaves the cluster, so the rumor of its departure spreads unrefuted.
+func (g *gossiper) leave() {
+	g.mu.Lock()
+	g.left = true
+	g.mu.Unlock()
+}
+
+// table returns the gossip of the node: the rumors about every member, including the node itself.
+func (n *node) table() *service.Gossip {
+	g := n.gossiper
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	t := &service.Gossip{Name: n.name, Rumors: []*service.Rumor{
+		{Name: n.name, Address: n.ipAddress.String(), Health: int32(ALIVE), Incarnation: g.incarnation},
+	}}
+	for _, r := range g.rumors {
+		t.Rumors = append(t.Rumors, r)
+	}
+	return t
+}
+
+// gossip probes a random peer every interval and declares the peers SUSPECTED for too long DEAD, until the node stops.
+func (n *node) gossip() {
+	for _, m := range n.peers.members() {
+		n.gossiper.alive(m.Name, m.Address)
+	}
+	n.merge(nil)
+
+	ticker := time.NewTicker(n.gossiper.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-n.gossiper.done:
+			return
+		}
+
+		n.expire()
+		if names := n.peers.names(); len(names) > 0 {
+			go n.probe(names[rand.Intn(len(names))], names)
+		}
+	}
+}
+
+// probe probes the peer target directly, and otherwise indirectly through up to Probes of the other peers.
+// If no probe is answered, the target is SUSPECTED. A peer which does not know the target does not probe it,
+// and is not counted as a failed probe.
+func (n *node) probe(target string, peers []string) {
+	if n.ping(n.peers.get(target)) {
+		return
+	}
+
+	n.logger.InfoPrintf("%v did not answer a probe. %v asks other members to probe it.\n", target, n.name)
+	helpers := make([]string, 0, len(peers))
+	for _, i := range rand.Perm(len(peers)) {
+		if peers[i] != target && len(helpers) < n.gossiper.Probes {
+			helpers = append(helpers, peers[i])
+		}
+	}
+
+	answers := make(chan error, len(helpers))
+	for _, helper := range helpers {
+		go func(helper string) {
+			ctx, cancel := context.WithTimeout(context.Background(), n.gossiper.Interval)
+			defer cancel()
+
+			t := n.table()
+			t.Target = target
+			reply, err := n.peers.get(helper).PingReq(ctx, t)
+			if err == nil {
+				n.merge(reply.Rumors)
+			}
+			answers <- err
+		}(helper)
+	}
+
+	failed := 0
+	for range helpers {
+		switch err := <-answers; {
+		case err == nil:
+			return
+		case status.Code(err) != codes.NotFound:
+			failed++
+		}
+	}
+
+	n.logger.InfoPrintf("%v did not answer the probes of %v other member(s) either.\n", target, failed)
+	n.suspect(target)
+}
+
+// ping sends the node's gossip to the peer c and merges the gossip of its answer.
+// It reports whether the peer answered within half an interval.
+func (n *node) ping(c service.ServiceClient) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), n.gossiper.Interval/2)
+	defer cancel()
+
+	reply, err := c.Ping(ctx, n.table())
+	if err != nil {
+		return false
+	}
+
+	n.merge(reply.Rumors)
+	return true
+}
+
+// suspect makes the peer name SUSPECTED, unless a later rumor about it is known.
+func (n *node) suspect(name string) {
+	g := n.gossiper
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	r, ok := g.rumors[name]
+	if !ok || r.Health != int32(ALIVE) {
+		return
+	}
+
+	g.rumors[name] = &service.Rumor{Name: name, Address: r.Address, Health: int32(SUSPECTED), Incarnation: r.Incarnation}
+	g.suspected[name] = time.Now()
+	n.logger.WarningPrintf("%v did not answer any probe. %v SUSPECTS it has failed.\n", name, n.name)
+}
+
+// expire declares every member which has been SUSPECTED for Suspect DEAD, and removes it from the peers.
+func (n *node) expire() {
+	g := n.gossiper
+	var dead []string
+
+	g.mu.Lock()
+	for name, since := range g.suspected {
+		if time.Since(since) < g.Suspect {
+			continue
+		}
+
+		r := g.rumors[name]
+		g.rumors[name] = &service.Rumor{Name: name, Address: r.Address, Health: int32(DEAD), Incarnation: r.Incarnation}
+		delete(g.suspected, name)
+		dead = append(dead, name)
+	}
+	g.mu.Unlock()
+
+	for _, name := range dead {
+		n.logger.WarningPrintf("%v has been SUSPECTED for %v. %v declared it DEAD.\n", name, g.Suspect, n.name)
+		n.bury(name)
+	}
+}
+
+// merge merges rumors into the node's membership table.
+// A rumor replaces the known rumor about a member if it is about a later incarnation,
+// or about the same incarnation and worse: ALIVE < SUSPECTED < DEAD.
+// A rumor suspecting the node itself is refuted by raising the node's incarnation.
+// DEAD members are removed from the peers, and live members which are not peers are joined once the node is ready.
+func (n *node) merge(rumors []*service.Rumor) {
+	g := n.gossiper
+	var introduce, bury []*service.Rumor
+
+	g.mu.Lock()
+	for _, r := range rumors {
+		if r.Name == n.name {
+			if r.Health != int32(ALIVE) && r.Incarnation >= g.incarnation && !g.left {
+				g.incarnation = r.Incarnation + 1
+				n.logger.WarningPrintf("%v is rumored to be %v. It refutes the rumor with incarnation %v.\n", n.name, health(int(r.Health)), g.incarnation)
+			}
+			continue
+		}
+
+		known, ok := g.rumors[r.Name]
+		if ok && r.Incarnation < known.Incarnation || ok && r.Incarnation == known.Incarnation && r.Health <= known.Health {
+			continue
+		}
+
+		r = &service.Rumor{Name: r.Name, Address: r.Address, Health: r.Health, Incarnation: r.Incarnation}
+		g.rumors[r.Name] = r
+		switch int(r.Health) {
+		case ALIVE:
+			delete(g.suspected, r.Name)
+		case SUSPECTED:
+			if _, ok := g.suspected[r.Name]; !ok {
+				g.suspected[r.Name] = time.Now()
+			}
+		case DEAD:
+			delete(g.suspected, r.Name)
+			bury = append(bury, r)
+		}
+	}
+
+	select {
+	case <-n.ready:
+		for _, r := range g.rumors {
+			if r.Health == int32(ALIVE) && !n.peers.has(r.Name) && !g.introducing[r.Name] {
+				g.introducing[r.Name] = true
+				introduce = append(introduce, r)
+			}
+		}
+	default:
+	}
+	g.mu.Unlock()
+
+	for _, r := range introduce {
+		go n.introduce(r.Name, r.Address)
+	}
+	for _, r := range bury {
+		n.bury(r.Name)
+	}
+}
+
+// introduce joins the member name at the given address, learnt from gossip, so both nodes add each other to their peers.
+// A member which cannot be joined is SUSPECTED.
+func (n *node) introduce(name string, address string) {
+	defer func() {
+		n.gossiper.mu.Lock()
+		delete(n.gossiper.introducing, name)
+		n.gossiper.mu.Unlock()
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*n.gossiper.Interval)
+	defer cancel()
+
+	if _, err := n.connect(ctx, address); err != nil {
+		n.logger.WarningPrintf("%v could not join %v, learnt from gossip. :: %v\n", n.name, name, err)
+		n.suspect(name)
+		return
+	}
+	n.logger.WarningPrintf("%v DISCOVERED %v THROUGH GOSSIP.", n.name, name)
+}
+
+// bury removes the DEAD member name from the peers, and reports whether it was a peer.
+func (n *node) bury(name string) bool {
+	n.joining.Lock()
+	defer n.joining.Unlock()
+
+	if !n.peers.remove(name) {
+		return false
+	}
+
+	n.detector.forget(name)
+	n.changed(name, false)
+	n.logger.WarningPrintf("%v WAS REMOVED FROM THE CLUSTER.", name)
+	return true
+}
+
+// health returns the name of the health h.
+func health(h int) string {
+	switch h {
+	case ALIVE:
+		return "ALIVE"
+	case SUSPECTED:
+		return "SUSPECTED"
+	default:
+		return "DEAD"
+	}
+}
+
+// Ping receives the gossip of a probing member, merges it and answers with the node's own gossip.
+func (n *node) Ping(_ context.Context, g *service.Gossip) (*service.Gossip, error) {
+	n.merge(g.Rumors)
+	return n.table(), nil
+}
+
+// PingReq probes the member g.Target on behalf of another member, and answers with the node's gossip if the target answered.
+// A target which is not a peer of the node is not probed, and answered with NotFound.
+func (n *node) PingReq(_ context.Context, g *service.Gossip) (*service.Gossip, error) {
+	n.merge(g.Rumors)
+	if !n.peers.has(g.Target) {
+		return nil, status.Errorf(codes.NotFound, "%v is not a peer of %v", g.Target, n.name)
+	}
+	if !n.ping(n.peers.get(g.Target)) {
+		return nil, status.Errorf(codes.Unavailable, "%v did not answer a probe by %v", g.Target, n.name)
+	}
+
+	return n.table(), nil
+}
@@ -0,0 +1,127 @@
+package dme
+
+import (
+	"context"
+	"fmt"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"mandatory-exercise-2/service"
+	"sync"
+	"testing"
+	"time"
+)
+
+// rumorAbout returns the health and incarnation of the member name as rumored at the node of m.
+func rumorAbout(m *Mutex, name string) (int, uint32, bool) {
+	g := m.node.gossiper
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	r, ok := g.rumors[name]
+	if !ok {
+		return DEAD, 0, false
+	}
+	return int(r.Health), r.Incarnation, true
+}
+
+// TestGossipDiscovery checks that a node which only knows a seed is added to the peers of every member through gossip,
+// and that a stopped node is SUSPECTED, then DEAD, and removed from the peers of every member.
+func TestGossipDiscovery(t *testing.T) {
+	config := Config{Gossip: Gossip{Interval: 50 * time.Millisecond, Suspect: 500 * time.Millisecond}}
+	ms := newCluster(t, 2, config)
+
+	late := newTestMutex(t, config, "d2", freePort(t))
+	var once sync.Once
+	stop := func() { once.Do(late.Stop) }
+	t.Cleanup(stop)
+	late.Start([]string{fmt.Sprint(ms[0].node.ipAddress.Port)})
+
+	all := []*Mutex{ms[0], ms[1], late}
+	eventually(t, 10*time.Second, func() bool {
+		for _, m := range all {
+			if m.node.peers.len() != len(all)-1 {
+				return false
+			}
+		}
+		return true
+	}, "the members did not all learn about each other through gossip")
+
+	stop()
+	suspected := make(map[string]bool)
+	eventually(t, 10*time.Second, func() bool {
+		buried := true
+		for _, m := range ms {
+			h, _, _ := rumorAbout(m, "d2")
+			suspected[m.Name()] = suspected[m.Name()] || h == SUSPECTED
+			buried = buried && h == DEAD && !m.node.peers.has("d2")
+		}
+		return buried
+	}, "the stopped node d2 was not declared DEAD and removed from the peers")
+
+	if !suspected["d0"] && !suspected["d1"] {
+		t.Error("the stopped node d2 was declared DEAD without being SUSPECTED first")
+	}
+}
+
+// TestGossipMerge checks that a rumor about a later incarnation of a member replaces the known one,
+// and that of two rumors about the same incarnation, the worse health wins.
+func TestGossipMerge(t *testing.T) {
+	m := newTestMutex(t, Config{Gossip: Gossip{Interval: time.Second}}, "d0", freePort(t))
+	rumor := func(health int, incarnation uint32) []*service.Rumor {
+		return []*service.Rumor{{Name: "d1", Address: "127.0.0.1:1", Health: int32(health), Incarnation: incarnation}}
+	}
+
+	for _, c := range []struct {
+		rumor       []*service.Rumor
+		health      int
+		incarnation uint32
+	}{
+		{rumor(ALIVE, 1), ALIVE, 1},
+		{rumor(SUSPECTED, 0), ALIVE, 1},     // An earlier incarnation is ignored.
+		{rumor(SUSPECTED, 1), SUSPECTED, 1}, // The same incarnation with a worse health wins.
+		{rumor(ALIVE, 1), SUSPECTED, 1},     // The same incarnation with a better health is ignored.
+		{rumor(ALIVE, 2), ALIVE, 2},         // A later incarnation wins, even with a better health.
+		{rumor(DEAD, 2), DEAD, 2},
+		{rumor(SUSPECTED, 2), DEAD, 2},
+	} {
+		m.node.merge(c.rumor)
+		h, incarnation, _ := rumorAbout(m, "d1")
+		if h != c.health || incarnation != c.incarnation {
+			t.Errorf("after merging %v, d1 is %v in incarnation %v, want %v in incarnation %v",
+				c.rumor[0], health(h), incarnation, health(c.health), c.incarnation)
+		}
+	}
+}
+
+// TestGossipRefute checks that a node refutes a rumor suspecting it by raising its incarnation, unless it has left.
+func TestGossipRefute(t *testing.T) {
+	m := newTestMutex(t, Config{Gossip: Gossip{Interval: time.Second}}, "d0", freePort(t))
+	self := func() *service.Rumor { return m.node.table().Rumors[0] }
+
+	m.node.merge([]*service.Rumor{{Name: "d0", Health: int32(SUSPECTED), Incarnation: 0}})
+	if r := self(); r.Health != int32(ALIVE) || r.Incarnation != 1 {
+		t.Errorf("d0 rumors itself %v in incarnation %v after being suspected, want ALIVE in incarnation 1", health(int(r.Health)), r.Incarnation)
+	}
+
+	m.node.merge([]*service.Rumor{{Name: "d0", Health: int32(DEAD), Incarnation: 0}})
+	if r := self(); r.Incarnation != 1 {
+		t.Errorf("d0 refuted a rumor about an earlier incarnation, raising its incarnation to %v", r.Incarnation)
+	}
+
+	m.node.gossiper.leave()
+	m.node.merge([]*service.Rumor{{Name: "d0", Health: int32(DEAD), Incarnation: 1}})
+	if r := self(); r.Incarnation != 1 {
+		t.Errorf("d0 refuted a rumor after leaving, raising its incarnation to %v", r.Incarnation)
+	}
+}
+
+// TestPingReqUnknownTarget checks that a node asked to probe a member which is not its peer answers NotFound,
+// rather than reporting that the member did not answer.
+func TestPingReqUnknownTarget(t *testing.T) {
+	m := newTestMutex(t, Config{Gossip: Gossip{Interval: time.Second}}, "d0", freePort(t))
+
+	_, err := m.node.PingReq(context.Background(), &service.Gossip{Name: "d1", Target: "d2"})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("PingReq of a target which is not a peer returned %v, want NotFound", err)
+	}
+}
@@ -0,0 +1,249 @@
+package dme
+
+import (
+	"context"
+	"mandatory-exercise-2/service"
+	"mandatory-exercise-2/utils"
+	"sync"
+)
+
+// lamportQueue is Lamport's original (1978) algorithm.
+// Every node keeps a copy of a queue of all requests, ordered by (lamport, name).
+// A node enters HELD when its own request is first in its queue and it has received a later message from every peer.
+// Requests are sent with Publish, replies with ReplySender and releases with Release, so an entry costs 3(N-1) messages.
+// Messages to a node must arrive in the order they were sent, which the outboxes guarantee.
+type lamportQueue struct {
+	*instance
+	mu        sync.Mutex         // mu guards all the fields below and the node's Lamport clock.
+	state     int                // The current state of the node.
+	timestamp int32              // timestamp is the Lamport time of the node's outstanding request.
+	queue     *utils.Queue       // queue is the requests of all nodes, including this one, ordered by (lamport, name).
+	latest    map[string]int32   // latest is the timestamp of the latest message received from each peer.
+	replied   chan struct{}      // replied is closed when every peer has sent a message later than the outstanding request.
+	acquired  chan struct{}      // acquired is closed when the node enters HELD.
+	outboxes  map[string]*outbox // outboxes are the outboxes to the other nodes.
+}
+
+// lock requests the lock from all peers and blocks until the node is in HELD, or until ctx is done.
+// If ctx is done first, the request is released again.
+func (lq *lamportQueue) lock(ctx context.Context) error {
+	_, acquired := lq.enter()
+
+	select {
+	case <-acquired:
+		return nil
+	case <-ctx.Done():
+	}
+
+	if lq.abandon() {
+		return nil
+	}
+	return ctx.Err()
+}
+
+// tryLock requests the lock from all peers and enters HELD only if the request is first in the queue
+// once every peer has replied.
+func (lq *lamportQueue) tryLock(ctx context.Context) bool {
+	replied, acquired := lq.enter()
+
+	select {
+	case <-replied:
+	case <-ctx.Done():
+	}
+
+	select {
+	case <-acquired:
+		return true
+	default:
+	}
+
+	lq.logger.InfoPrintf("%v could not get the lock straight away.\n", lq.name)
+	return lq.abandon()
+}
+
+// unlock leaves HELD and releases the request at all peers.
+func (lq *lamportQueue) unlock() {
+	lq.mu.Lock()
+	defer lq.mu.Unlock()
+
+	if lq.state != HELD {
+		lq.logger.WarningPrintf("%v tried to unlock without holding the lock.\n", lq.name)
+		return
+	}
+
+	lq.release()
+	<-lq.local
+}
+
+// enter makes the node enter WANTED, timestamps its request, queues it and sends it to all peers.
+// It returns the channels replied and acquired of the request.
+func (lq *lamportQueue) enter() (<-chan struct{}, <-chan struct{}) {
+	<-lq.ready
+	lq.mu.Lock()
+	defer lq.mu.Unlock()
+
+	lq.state = WANTED
+	lq.lamport.Increment()
+	lq.timestamp = lq.lamport.Value()
+	lq.replied = make(chan struct{})
+	lq.acquired = make(chan struct{})
+	lq.logger.InfoPrintf("%v entered WANTED\n", lq.name)
+
+	lq.queue.Insert(lq.timestamp, lq.name)
+	r := &service.Request{Lamport: lq.timestamp, Name: lq.name, Resource: lq.resource}
+	lq.logger.InfoPrintf("(%v, Send) %v is sending a request to all peers.\n", r.Lamport, lq.name)
+	for _, name := range lq.peers.names() {
+		lq.send(name, func(c service.ServiceClient) error {
+			_, err := c.Publish(context.Background(), r)
+			return err
+		})
+	}
+
+	lq.check()
+	return lq.replied, lq.acquired
+}
+
+// abandon releases the outstanding request, unless the node has entered HELD in the meantime.
+// It reports whether the node is in HELD.
+func (lq *lamportQueue) abandon() bool {
+	lq.mu.Lock()
+	defer lq.mu.Unlock()
+
+	if lq.state == HELD {
+		return true
+	}
+
+	lq.logger.InfoPrintf("%v abandoned its request.\n", lq.name)
+	lq.release()
+	<-lq.local
+	return false
+}
+
+// release makes the node enter RELEASED, removes its request from the queue and sends a release to all peers.
+// The mutex must be held.
+func (lq *lamportQueue) release() {
+	lq.state = RELEASED
+	lq.logger.InfoPrintf("%v entered RELEASED\n", lq.name)
+	lq.queue.Remove(lq.name)
+
+	lq.lamport.Increment()
+	r := &service.Request{Lamport: lq.lamport.Value(), Name: lq.name, Resource: lq.resource}
+	lq.logger.InfoPrintf("(%v, Send) %v is sending a release to all peers.\n", r.Lamport, lq.name)
+	for _, name := range lq.peers.names() {
+		lq.send(name, func(c service.ServiceClient) error {
+			_, err := c.Release(context.Background(), r)
+			return err
+		})
+	}
+}
+
+// check closes replied when every peer has sent a message later than the outstanding request,
+// and enters HELD if the request is also first in the queue. The mutex must be held.
+func (lq *lamportQueue) check() {
+	if lq.state != WANTED {
+		return
+	}
+
+	for _, name := range lq.peers.names() {
+		if !utils.Before(lq.timestamp, lq.name, lq.latest[name], name) {
+			return
+		}
+	}
+
+	select {
+	case <-lq.replied:
+	default:
+		close(lq.replied)
+	}
+
+	if _, first := lq.queue.Front(); first != lq.name {
+		return
+	}
+
+	lq.state = HELD
+	lq.logger.InfoPrintf("%v entered HELD\n", lq.name)
+	lq.logger.InfoPrintf("%v IS NOW IN THE CRITICAL SECTION!\n", lq.name)
+	close(lq.acquired)
+}
+
+// receive updates the Lamport clock and the latest message of the node name with a received timestamp.
+// The mutex must be held.
+func (lq *lamportQueue) receive(lamport int32, name string) {
+	lq.lamport.MaxAndIncrement(lamport)
+	if lamport > lq.latest[name] {
+		lq.latest[name] = lamport
+	}
+}
+
+// send posts a message to the node to.
+func (lq *lamportQueue) send(to string, message func(c service.ServiceClient) error) {
+	o, ok := lq.outboxes[to]
+	if !ok {
+		o = newOutbox()
+		lq.outboxes[to] = o
+	}
+
+	o.post(func() {
+		if err := message(lq.peers.get(to)); err != nil {
+			lq.logger.ErrorPrintf("Error sending message to %v. :: %v\n", to, err)
+		}
+	})
+}
+
+// Publish receives a request from another node, queues it and replies with a timestamped reply.
+func (lq *lamportQueue) Publish(_ context.Context, r *service.Request) (*service.Reply, error) {
+	<-lq.ready
+	lq.mu.Lock()
+	defer lq.mu.Unlock()
+	lq.logger.InfoPrintf("(%v, Receive) %v received request from %v.\n", r.Lamport, lq.name, r.Name)
+
+	lq.receive(r.Lamport, r.Name)
+	lq.queue.Insert(r.Lamport, r.Name)
+
+	lq.lamport.Increment()
+	reply := &service.Request{Lamport: lq.lamport.Value(), Name: lq.name, Resource: lq.resource}
+	lq.logger.InfoPrintf("(%v, Send) %v is replying %v.\n", reply.Lamport, lq.name, r.Name)
+	lq.send(r.Name, func(c service.ServiceClient) error {
+		_, err := c.ReplySender(context.Background(), reply)
+		return err
+	})
+
+	lq.check()
+	return &service.Reply{}, nil
+}
+
+// ReplySender receives a reply to the node's request from another node.
+func (lq *lamportQueue) ReplySender(_ context.Context, r *service.Request) (*service.Reply, error) {
+	<-lq.ready
+	lq.mu.Lock()
+	defer lq.mu.Unlock()
+	lq.logger.InfoPrintf("(%v, Receive) %v received reply from %v.\n", r.Lamport, lq.name, r.Name)
+
+	lq.receive(r.Lamport, r.Name)
+	lq.check()
+	return &service.Reply{}, nil
+}
+
+// Release receives a release from another node and removes its request from the queue.
+func (lq *lamportQueue) Release(_ context.Context, r *service.Request) (*service.Reply, error) {
+	<-lq.ready
+	lq.mu.Lock()
+	defer lq.mu.Unlock()
+	lq.logger.InfoPrintf("(%v, Receive) %v received release from %v.\n", r.Lamport, lq.name, r.Name)
+
+	lq.receive(r.Lamport, r.Name)
+	lq.queue.Remove(r.Name)
+	lq.check()
+	return &service.Reply{}, nil
+}
+
+// newLamportQueue creates Lamport's algorithm for the instance i.
+func newLamportQueue(i *instance) *lamportQueue {
+	return &lamportQueue{
+		instance: i,
+		state:    RELEASED,
+		queue:    utils.NewQueue(),
+		latest:   make(map[string]int32),
+		outboxes: make(map[string]*outbox),
+	}
+}
@@ -0,0 +1,386 @@
+package dme
+
+import (
+	"context"
+	"mandatory-exercise-2/service"
+	"mandatory-exercise-2/utils"
+	"sync"
+)
+
+// The messages of Maekawa's algorithm.
+const (
+	REQUEST    = "REQUEST"
+	LOCKED     = "LOCKED"
+	FAILED     = "FAILED"
+	INQUIRE    = "INQUIRE"
+	RELINQUISH = "RELINQUISH"
+	RELEASE    = "RELEASE"
+)
+
+// A vote is a request waiting for, or holding, the vote of a voter.
+type vote struct {
+	lamport int32  // lamport is the timestamp of the request.
+	name    string // name is the name of the requesting node.
+	failed  bool   // failed is true if the requesting node knows it does not have the vote.
+}
+
+// maekawa is Maekawa's quorum based algorithm with Sanders' INQUIRE/RELINQUISH handling of deadlocks.
+// Every node has a voting set (quorum) from a grid of all nodes, and every node is a voter for the nodes whose voting set it is in.
+// A node enters HELD when every voter in its quorum has locked its vote for it.
+type maekawa struct {
+	*instance
+	mu sync.Mutex // mu guards all the fields below.
+
+	// The node as a requester.
+	state     int             // The current state of the node.
+	timestamp int32           // timestamp is the Lamport time of the node's outstanding request.
+	quorum    []string        // quorum is the voting set of the node.
+	granted   map[string]bool // granted are the voters which have locked their vote for the outstanding request.
+	failed    map[string]bool // failed are the voters which have answered FAILED to the outstanding request.
+	inquired  map[string]bool // inquired are the granting voters which have asked for their vote back.
+	yielded   bool            // yielded is true if the node has relinquished a vote for the outstanding request.
+	acquired  chan struct{}   // acquired is closed when the node enters HELD.
+	refused   chan struct{}   // refused is closed at the first FAILED for the outstanding request.
+
+	// The node as a voter.
+	lockedBy  *vote   // lockedBy is the request which holds the node's vote, or nil.
+	waiting   []*vote // waiting are the requests waiting for the node's vote, ordered by (lamport, name).
+	inquiring bool    // inquiring is true if an INQUIRE has been sent to lockedBy.
+
+	outboxes map[string]*outbox    // outboxes are the outboxes to the other nodes, including this node.
+	self     service.ServiceClient // self is a client to this node, used to vote for itself.
+	selfOnce sync.Once
+}
+
+// lock blocks until all voters in the node's quorum have voted for it, or until ctx is done.
+// If ctx is done first, the request is withdrawn from all voters.
+func (mk *maekawa) lock(ctx context.Context) error {
+	acquired, _ := mk.request()
+
+	select {
+	case <-acquired:
+		return nil
+	case <-ctx.Done():
+		mk.release()
+		<-mk.local
+		return ctx.Err()
+	}
+}
+
+// tryLock enters HELD unless a voter answers FAILED, i.e. unless the vote is held by an older request.
+// A voter which asks another node for its vote back answers neither, so tryLock may wait until ctx is done.
+func (mk *maekawa) tryLock(ctx context.Context) bool {
+	acquired, refused := mk.request()
+
+	select {
+	case <-acquired:
+		return true
+	case <-refused:
+	case <-ctx.Done():
+	}
+
+	mk.logger.InfoPrintf("%v could not get the lock straight away.\n", mk.name)
+	mk.release()
+	<-mk.local
+	return false
+}
+
+// unlock leaves HELD and releases the votes of the quorum.
+func (mk *maekawa) unlock() {
+	mk.mu.Lock()
+	state := mk.state
+	mk.mu.Unlock()
+
+	if state != HELD {
+		mk.logger.WarningPrintf("%v tried to unlock without holding the lock.\n", mk.name)
+		return
+	}
+
+	mk.release()
+	<-mk.local
+}
+
+// request makes the node enter WANTED and sends a timestamped REQUEST to every voter in its quorum.
+// It returns the acquired and refused channels of the request.
+func (mk *maekawa) request() (<-chan struct{}, <-chan struct{}) {
+	defer mk.mu.Unlock()
+	mk.mu.Lock()
+
+	if mk.quorum == nil {
+		mk.quorum = gridQuorum(mk.members(), mk.name)
+		mk.logger.InfoPrintf("%v has the voting set %v\n", mk.name, mk.quorum)
+	}
+
+	mk.lamport.Increment()
+	mk.timestamp = mk.lamport.Value()
+	mk.state = WANTED
+	mk.granted = make(map[string]bool)
+	mk.failed = make(map[string]bool)
+	mk.inquired = make(map[string]bool)
+	mk.yielded = false
+	mk.acquired = make(chan struct{})
+	mk.refused = make(chan struct{})
+	mk.logger.InfoPrintf("%v entered WANTED\n", mk.name)
+
+	for _, voter := range mk.quorum {
+		mk.send(voter, REQUEST, mk.timestamp)
+	}
+
+	return mk.acquired, mk.refused
+}
+
+// release makes the node enter RELEASED and sends a RELEASE to every voter in its quorum.
+// Voters which hold a vote for the node give it to the next request, and all others forget the request.
+func (mk *maekawa) release() {
+	defer mk.mu.Unlock()
+	mk.mu.Lock()
+
+	mk.state = RELEASED
+	mk.logger.InfoPrintf("%v entered RELEASED\n", mk.name)
+
+	for _, voter := range mk.quorum {
+		mk.send(voter, RELEASE, mk.timestamp)
+	}
+}
+
+// relinquish gives the vote of voter back. The mutex must be held.
+func (mk *maekawa) relinquish(voter string) {
+	delete(mk.granted, voter)
+	delete(mk.inquired, voter)
+	mk.yielded = true
+	mk.send(voter, RELINQUISH, mk.timestamp)
+}
+
+// outstanding reports whether a message with timestamp lamport is about the node's request in WANTED. The mutex must be held.
+func (mk *maekawa) outstanding(lamport int32) bool {
+	return mk.state == WANTED && lamport == mk.timestamp
+}
+
+// grant locks the node's vote for v. The mutex must be held.
+func (mk *maekawa) grant(v *vote) {
+	mk.lockedBy = v
+	mk.inquiring = false
+	mk.send(v.name, LOCKED, v.lamport)
+}
+
+// enqueue inserts v in waiting, ordered by (lamport, name). The mutex must be held.
+func (mk *maekawa) enqueue(v *vote) {
+	i := 0
+	for i < len(mk.waiting) && utils.Before(mk.waiting[i].lamport, mk.waiting[i].name, v.lamport, v.name) {
+		i++
+	}
+	mk.waiting = append(mk.waiting, nil)
+	copy(mk.waiting[i+1:], mk.waiting[i:])
+	mk.waiting[i] = v
+}
+
+// dequeue removes and returns the first request in waiting. The mutex must be held.
+func (mk *maekawa) dequeue() *vote {
+	v := mk.waiting[0]
+	mk.waiting = mk.waiting[1:]
+	return v
+}
+
+// send posts a message of the given kind to the node to.
+func (mk *maekawa) send(to string, kind string, lamport int32) {
+	o, ok := mk.outboxes[to]
+	if !ok {
+		o = newOutbox()
+		mk.outboxes[to] = o
+	}
+
+	mk.logger.InfoPrintf("(%v, Send) %v sends %v to %v.\n", lamport, mk.name, kind, to)
+	o.post(func() {
+		c := mk.client(to)
+		r := &service.Request{Lamport: lamport, Name: mk.name, Resource: mk.resource}
+
+		var err error
+		switch kind {
+		case REQUEST:
+			_, err = c.Request(context.Background(), r)
+		case LOCKED:
+			_, err = c.Locked(context.Background(), r)
+		case FAILED:
+			_, err = c.Failed(context.Background(), r)
+		case INQUIRE:
+			_, err = c.Inquire(context.Background(), r)
+		case RELINQUISH:
+			_, err = c.Relinquish(context.Background(), r)
+		case RELEASE:
+			_, err = c.Release(context.Background(), r)
+		}
+
+		if err != nil {
+			mk.logger.ErrorPrintf("Error sending %v to %v. :: %v\n", kind, to, err)
+		}
+	})
+}
+
+// client returns the service.ServiceClient of the node name.
+func (mk *maekawa) client(name string) service.ServiceClient {
+	if name != mk.name {
+		return mk.peers.get(name)
+	}
+
+	mk.selfOnce.Do(func() {
+		mk.self = mk.dial(mk.ipAddress.String())
+	})
+	return mk.self
+}
+
+// Request receives a REQUEST for the node's vote.
+// The vote is locked for the request if it is free. Otherwise the request waits, and
+// if it is older than the request holding the vote (and all other waiting requests) the holder is asked to give the vote back.
+// Every other waiting request is answered with FAILED.
+func (mk *maekawa) Request(_ context.Context, r *service.Request) (*service.Reply, error) {
+	defer mk.mu.Unlock()
+	mk.mu.Lock()
+	mk.logger.InfoPrintf("(%v, Receive) %v received REQUEST from %v.\n", r.Lamport, mk.name, r.Name)
+	mk.lamport.MaxAndIncrement(r.Lamport)
+
+	v := &vote{lamport: r.Lamport, name: r.Name}
+	if mk.lockedBy == nil {
+		mk.grant(v)
+		return &service.Reply{}, nil
+	}
+
+	if utils.Before(v.lamport, v.name, mk.lockedBy.lamport, mk.lockedBy.name) &&
+		(len(mk.waiting) == 0 || utils.Before(v.lamport, v.name, mk.waiting[0].lamport, mk.waiting[0].name)) {
+		if len(mk.waiting) > 0 && !mk.waiting[0].failed {
+			mk.waiting[0].failed = true
+			mk.send(mk.waiting[0].name, FAILED, mk.waiting[0].lamport)
+		}
+
+		mk.enqueue(v)
+		if !mk.inquiring {
+			mk.inquiring = true
+			mk.send(mk.lockedBy.name, INQUIRE, mk.lockedBy.lamport)
+		}
+		return &service.Reply{}, nil
+	}
+
+	v.failed = true
+	mk.enqueue(v)
+	mk.send(v.name, FAILED, v.lamport)
+	return &service.Reply{}, nil
+}
+
+// Locked receives a voter's vote. The node enters HELD when it has the vote of its whole quorum.
+func (mk *maekawa) Locked(_ context.Context, r *service.Request) (*service.Reply, error) {
+	defer mk.mu.Unlock()
+	mk.mu.Lock()
+	mk.logger.InfoPrintf("(%v, Receive) %v received LOCKED from %v.\n", r.Lamport, mk.name, r.Name)
+
+	if !mk.outstanding(r.Lamport) {
+		return &service.Reply{}, nil
+	}
+
+	mk.granted[r.Name] = true
+	delete(mk.failed, r.Name)
+
+	if len(mk.granted) == len(mk.quorum) {
+		mk.state = HELD
+		mk.logger.InfoPrintf("%v entered HELD\n", mk.name)
+		mk.logger.InfoPrintf("%v IS NOW IN THE CRITICAL SECTION!\n", mk.name)
+		close(mk.acquired)
+	}
+
+	return &service.Reply{}, nil
+}
+
+// Failed receives a FAILED from a voter whose vote is held by an older request.
+// All votes which have been asked back are relinquished.
+func (mk *maekawa) Failed(_ context.Context, r *service.Request) (*service.Reply, error) {
+	defer mk.mu.Unlock()
+	mk.mu.Lock()
+	mk.logger.InfoPrintf("(%v, Receive) %v received FAILED from %v.\n", r.Lamport, mk.name, r.Name)
+
+	if !mk.outstanding(r.Lamport) {
+		return &service.Reply{}, nil
+	}
+
+	select {
+	case <-mk.refused:
+	default:
+		close(mk.refused)
+	}
+	mk.failed[r.Name] = true
+
+	for voter := range mk.inquired {
+		mk.relinquish(voter)
+	}
+
+	return &service.Reply{}, nil
+}
+
+// Inquire receives an INQUIRE from a voter which wants its vote back for an older request.
+// The vote is relinquished straight away if the node cannot get the lock anyway, otherwise when it does.
+func (mk *maekawa) Inquire(_ context.Context, r *service.Request) (*service.Reply, error) {
+	defer mk.mu.Unlock()
+	mk.mu.Lock()
+	mk.logger.InfoPrintf("(%v, Receive) %v received INQUIRE from %v.\n", r.Lamport, mk.name, r.Name)
+
+	if !mk.outstanding(r.Lamport) || !mk.granted[r.Name] {
+		return &service.Reply{}, nil
+	}
+
+	if len(mk.failed) > 0 || mk.yielded {
+		mk.relinquish(r.Name)
+	} else {
+		mk.inquired[r.Name] = true
+	}
+
+	return &service.Reply{}, nil
+}
+
+// Relinquish receives a vote given back by the node holding it.
+// The vote is locked for the oldest waiting request.
+func (mk *maekawa) Relinquish(_ context.Context, r *service.Request) (*service.Reply, error) {
+	defer mk.mu.Unlock()
+	mk.mu.Lock()
+	mk.logger.InfoPrintf("(%v, Receive) %v received RELINQUISH from %v.\n", r.Lamport, mk.name, r.Name)
+
+	if mk.lockedBy == nil || mk.lockedBy.name != r.Name || mk.lockedBy.lamport != r.Lamport {
+		return &service.Reply{}, nil
+	}
+
+	mk.lockedBy.failed = true
+	mk.enqueue(mk.lockedBy)
+	mk.grant(mk.dequeue())
+	return &service.Reply{}, nil
+}
+
+// Release receives a RELEASE from a node leaving HELD or withdrawing its request.
+// If the node holds the vote, the vote is locked for the oldest waiting request. Otherwise the request is forgotten.
+func (mk *maekawa) Release(_ context.Context, r *service.Request) (*service.Reply, error) {
+	defer mk.mu.Unlock()
+	mk.mu.Lock()
+	mk.logger.InfoPrintf("(%v, Receive) %v received RELEASE from %v.\n", r.Lamport, mk.name, r.Name)
+
+	if mk.lockedBy != nil && mk.lockedBy.name == r.Name && mk.lockedBy.lamport == r.Lamport {
+		mk.lockedBy = nil
+		mk.inquiring = false
+		if len(mk.waiting) > 0 {
+			mk.grant(mk.dequeue())
+		}
+		return &service.Reply{}, nil
+	}
+
+	for i, v := range mk.waiting {
+		if v.name == r.Name && v.lamport == r.Lamport {
+			mk.waiting = append(mk.waiting[:i], mk.waiting[i+1:]...)
+			break
+		}
+	}
+
+	return &service.Reply{}, nil
+}
+
+// newMaekawa creates Maekawa's algorithm for the instance i.
+func newMaekawa(i *instance) *maekawa {
+	return &maekawa{
+		instance: i,
+		state:    RELEASED,
+		outboxes: make(map[string]*outbox),
+	}
+}
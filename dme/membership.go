@@ -0,0 +1,269 @@
+package dme
+
+import (
+	"context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"mandatory-exercise-2/client"
+	"mandatory-exercise-2/service"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// A peerSet is the set of the other nodes in the cluster, which changes as nodes join and leave.
+type peerSet struct {
+	mu        sync.RWMutex                     // mu guards clients and addresses.
+	clients   map[string]service.ServiceClient // clients maps the name of each peer to a service.ServiceClient.
+	addresses map[string]string                // addresses maps the name of each peer to its ip address.
+}
+
+// get returns the client of the peer name.
+// If it is not a peer, e.g. because it has left the cluster, every call to the returned client fails.
+func (p *peerSet) get(name string) service.ServiceClient {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	c, ok := p.clients[name]
+	if !ok {
+		return service.NewServiceClient(departed(name))
+	}
+	return c
+}
+
+// names returns the names of all peers, sorted.
+func (p *peerSet) names() []string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	names := make([]string, 0, len(p.clients))
+	for name := range p.clients {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// has reports whether name is a peer.
+func (p *peerSet) has(name string) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	_, ok := p.clients[name]
+	return ok
+}
+
+// len returns the number of peers.
+func (p *peerSet) len() int {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return len(p.clients)
+}
+
+// members returns every peer as a service.Member.
+func (p *peerSet) members() []*service.Member {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	members := make([]*service.Member, 0, len(p.clients))
+	for name, address := range p.addresses {
+		members = append(members, &service.Member{Name: name, Address: address})
+	}
+	return members
+}
+
+// add adds the peer name at the given ip address, or replaces it. It reports whether the peer was replaced.
+func (p *peerSet) add(name string, address string, c service.ServiceClient) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	_, replaced := p.clients[name]
+	p.clients[name] = c
+	p.addresses[name] = address
+	return replaced
+}
+
+// remove removes the peer name. It reports whether it was a peer.
+func (p *peerSet) remove(name string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	_, ok := p.clients[name]
+	delete(p.clients, name)
+	delete(p.addresses, name)
+	return ok
+}
+
+// departed is the connection to a node which is not a peer. Every call to it fails.
+type departed string
+
+func (d departed) Invoke(context.Context, string, interface{}, interface{}, ...grpc.CallOption) error {
+	return status.Errorf(codes.Unavailable, "%v is not a member of the cluster", string(d))
+}
+
+func (d departed) NewStream(context.Context, *grpc.StreamDesc, string, ...grpc.CallOption) (grpc.ClientStream, error) {
+	return nil, status.Errorf(codes.Unavailable, "%v is not a member of the cluster", string(d))
+}
+
+// newPeerSet creates an empty peerSet.
+func newPeerSet() *peerSet {
+	return &peerSet{
+		clients:   make(map[string]service.ServiceClient),
+		addresses: make(map[string]string),
+	}
+}
+
+// A member is an algorithm which supports nodes joining and leaving the cluster while it runs.
+type member interface {
+	joined(name string) // joined is called when the peer name has joined the cluster.
+	left(name string)   // left is called when the peer name has left the cluster.
+	leave() error       // leave hands off everything the node owes its peers before it leaves the cluster.
+}
+
+// join starts the node's server for the algorithm and joins the cluster of the node at the seed address:
+// the node joins the seed, learns the other members of the cluster from it and joins each of them in turn,
+// so that every member adds the node to its peers.
+func (n *node) join(seed string, algorithm service.ServiceServer) {
+	n.logger.WarningPrintln("STARTING NODE...")
+	n.server.Start(n.ipAddress.String(), algorithm)
+	n.logger.WarningPrintln("NODE STARTED.")
+
+	if !strings.Contains(seed, ":") {
+		seed = DefaultAddress + ":" + seed
+	}
+
+	nq := &service.NameRequest{Name: n.name, Algorithm: n.algorithm, K: int32(n.k), Epoch: n.epoch, Address: n.ipAddress.String()}
+	known := map[string]bool{n.name: true}
+	pending := []string{seed}
+	for len(pending) > 0 {
+		address := pending[0]
+		pending = pending[1:]
+
+		peer := n.dial(address)
+		from, certified := n.certified()
+		reply, err := peer.Join(context.Background(), nq, from)
+		if err != nil {
+			n.logger.ErrorFatalf("Could not join the cluster at %v. :: %v", address, err)
+		}
+		if err := certified(reply.Name); err != nil {
+			n.logger.ErrorFatalf("The member at %v is not certified as %v. Refusing to join it. :: %v", address, reply.Name, err)
+		}
+
+		known[reply.Name] = true
+		n.peers.add(reply.Name, address, peer)
+		n.logger.InfoPrintf("%v joined %v.\n", n.name, reply.Name)
+
+		for _, m := range reply.Members {
+			if !known[m.Name] {
+				known[m.Name] = true
+				pending = append(pending, m.Address)
+			}
+		}
+	}
+
+	n.logger.WarningPrintf("%v JOINED THE CLUSTER OF %v NODES.", n.name, n.peers.len()+1)
+	n.metrics.Peers(n.peers.len())
+	n.restore()
+	close(n.ready)
+
+	if n.detector.enabled() {
+		go n.detect()
+	}
+	if n.gossiper.enabled() {
+		go n.gossip()
+	}
+}
+
+// leave tells every peer that the node leaves the cluster, and stops it.
+func (n *node) leave() {
+	n.gossiper.leave()
+	r := &service.Request{Name: n.name}
+	for _, name := range n.peers.names() {
+		if _, err := n.peers.get(name).Leave(context.Background(), r); err != nil {
+			n.logger.ErrorPrintf("Could not leave %v. :: %v\n", name, err)
+		}
+	}
+
+	n.logger.WarningPrintf("%v LEFT THE CLUSTER.", n.name)
+	n.stop()
+}
+
+// connect joins the node at the given ip address, so both nodes add each other to their peers, and returns its name.
+func (n *node) connect(ctx context.Context, address string) (string, error) {
+	peer, err := client.Dial(ctx, address, n.logger, n.dialOptions()...)
+	if err != nil {
+		return "", err
+	}
+
+	nq := &service.NameRequest{Name: n.name, Algorithm: n.algorithm, K: int32(n.k), Epoch: n.epoch, Address: n.ipAddress.String()}
+	from, certified := n.certified()
+	reply, err := peer.Join(ctx, nq, from)
+	if err != nil {
+		return "", err
+	}
+	if err := certified(reply.Name); err != nil {
+		return "", err
+	}
+
+	n.joining.Lock()
+	defer n.joining.Unlock()
+
+	if n.peers.add(reply.Name, address, peer) {
+		n.changed(reply.Name, false)
+	}
+	n.detector.heard(reply.Name)
+	n.changed(reply.Name, true)
+	return reply.Name, nil
+}
+
+// Join adds a joining node to the peers and returns the other members of the cluster.
+// A node with the name of a peer replaces it, e.g. after a restart; the peer is treated as having left first.
+// A node which would be refused as a peer by GetName is refused, and so is every node if the algorithm does not support joining.
+func (n *node) Join(ctx context.Context, nq *service.NameRequest) (*service.JoinReply, error) {
+	n.logger.InfoPrintf("%v at %v is joining %v.\n", nq.Name, nq.Address, n.name)
+
+	if err := n.authenticate(ctx, nq.Name); err != nil {
+		return nil, err
+	}
+	if err := n.admit(nq); err != nil {
+		return nil, err
+	}
+
+	if n.algorithm != RICART_AGRAWALA {
+		n.logger.WarningPrintf("%v does not support joining nodes. Refusing %v.", n.algorithm, nq.Name)
+		return nil, status.Errorf(codes.FailedPrecondition, "%v does not support joining nodes", n.algorithm)
+	}
+
+	n.joining.Lock()
+	defer n.joining.Unlock()
+
+	members := n.peers.members()
+	peer := n.dial(nq.Address)
+	if n.peers.add(nq.Name, nq.Address, peer) {
+		n.logger.WarningPrintf("%v rejoined the cluster.", nq.Name)
+		n.changed(nq.Name, false)
+	}
+	n.detector.heard(nq.Name)
+	n.gossiper.alive(nq.Name, nq.Address)
+	n.changed(nq.Name, true)
+	n.logger.WarningPrintf("%v JOINED THE CLUSTER.", nq.Name)
+
+	return &service.JoinReply{Name: n.name, Members: members}, nil
+}
+
+// Leave removes a leaving node from the peers.
+func (n *node) Leave(_ context.Context, r *service.Request) (*service.Reply, error) {
+	n.joining.Lock()
+	defer n.joining.Unlock()
+
+	n.gossiper.dead(r.Name)
+	if n.peers.remove(r.Name) {
+		n.detector.forget(r.Name)
+		n.changed(r.Name, false)
+		n.logger.WarningPrintf("%v LEFT THE CLUSTER.", r.Name)
+	}
+
+	return &service.Reply{}, nil
+}
@@ -0,0 +1,75 @@
+package dme
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// stateOf returns the Ricart-Agrawala state of the node of m for the DefaultResource.
+func stateOf(m *Mutex) int {
+	ra := m.Resource(DefaultResource).algorithm.(*ricartAgrawala)
+	ra.mu.Lock()
+	defer ra.mu.Unlock()
+
+	return ra.state
+}
+
+// TestJoinLeave checks that a node joining through a seed is added to the peers of every member,
+// that it cannot leave while it is WANTED or HELD, and that the remaining members still exclude each other after it left.
+func TestJoinLeave(t *testing.T) {
+	ms := newCluster(t, 2, Config{})
+	joiner := newTestMutex(t, Config{}, "d2", freePort(t))
+	var once sync.Once
+	t.Cleanup(func() { once.Do(joiner.Stop) })
+	joiner.Join(fmt.Sprint(ms[0].node.ipAddress.Port))
+
+	for _, m := range []*Mutex{ms[0], ms[1], joiner} {
+		if got := m.Members(); !reflect.DeepEqual(got, []string{"d0", "d1", "d2"}) {
+			t.Errorf("%v has the members %v after d2 joined, want d0, d1 and d2", m.Name(), got)
+		}
+	}
+
+	// HELD
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+	if err := joiner.Lock(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := joiner.Leave(); !errors.Is(err, ErrBusy) {
+		t.Errorf("Leave returned %v while d2 is HELD, want ErrBusy", err)
+	}
+	joiner.Unlock()
+
+	// WANTED
+	if err := ms[0].Lock(ctx); err != nil {
+		t.Fatal(err)
+	}
+	locked := make(chan error, 1)
+	go func() { locked <- joiner.Lock(ctx) }()
+	eventually(t, 5*time.Second, func() bool { return stateOf(joiner) == WANTED }, "d2 did not request the lock")
+	if err := joiner.Leave(); !errors.Is(err, ErrBusy) {
+		t.Errorf("Leave returned %v while d2 is WANTED, want ErrBusy", err)
+	}
+	ms[0].Unlock()
+	if err := <-locked; err != nil {
+		t.Fatal(err)
+	}
+	joiner.Unlock()
+
+	if err := joiner.Leave(); err != nil {
+		t.Fatalf("Leave returned %v while d2 is RELEASED", err)
+	}
+	once.Do(func() {})
+
+	for _, m := range ms {
+		if got := m.Members(); !reflect.DeepEqual(got, []string{"d0", "d1"}) {
+			t.Errorf("%v has the members %v after d2 left, want d0 and d1", m.Name(), got)
+		}
+	}
+	contend(t, ms, 10)
+}
@@ -0,0 +1,34 @@
+package dme
+
+import "time"
+
+// A Metrics collects the statistics of a node, e.g. to export them to Prometheus.
+// All methods but Peers take the name of the resource. They must be safe for concurrent use, and return quickly,
+// as the algorithm calls them while holding its locks.
+// RICART_AGRAWALA reports everything; the other algorithms only report the number of peers.
+type Metrics interface {
+	RequestSent(resource string)             // RequestSent is called when the node sends a request to a peer.
+	RequestReceived(resource string)         // RequestReceived is called when the node receives a request from a peer.
+	ReplySent(resource string)               // ReplySent is called when the node replies to a request, straight away or after deferring it.
+	ReplyReceived(resource string)           // ReplyReceived is called when the node receives a reply to its request.
+	Deferred(resource string)                // Deferred is called when the node defers a request.
+	Wanted(resource string, d time.Duration) // Wanted is called when the node leaves WANTED after d, holding the lock or giving up.
+	Held(resource string, d time.Duration)   // Held is called when the node releases the lock after holding it for d.
+	QueueLength(resource string, length int) // QueueLength is called whenever the length of the node's queue changes.
+	Clock(resource string, clock int32)      // Clock is called whenever the node's Lamport clock has ticked.
+	Peers(peers int)                         // Peers is called whenever the number of peers of the node changes.
+}
+
+// noMetrics is the Metrics of a node without metrics, which discards everything.
+type noMetrics struct{}
+
+func (noMetrics) RequestSent(string)           {}
+func (noMetrics) RequestReceived(string)       {}
+func (noMetrics) ReplySent(string)             {}
+func (noMetrics) ReplyReceived(string)         {}
+func (noMetrics) Deferred(string)              {}
+func (noMetrics) Wanted(string, time.Duration) {}
+func (noMetrics) Held(string, time.Duration)   {}
+func (noMetrics) QueueLength(string, int)      {}
+func (noMetrics) Clock(string, int32)          {}
+func (noMetrics) Peers(int)                    {}
@@ -0,0 +1,316 @@
+// Package dme implements distributed mutual exclusion between nodes.
+// The algorithm used by the cluster is chosen with Config.Algorithm.
+package dme
+
+import (
+	"context"
+	"errors"
+	"go.opentelemetry.io/otel/trace"
+	"mandatory-exercise-2/service"
+	"mandatory-exercise-2/utils"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultAddress is the address used for peers given only by their port.
+const DefaultAddress = "127.0.0.1"
+
+// Constants which represent the different states of a node.
+// HELD is the state of a node holding an exclusive lock, i.e. WRITE_HELD.
+const (
+	WANTED     int = 0
+	WRITE_HELD     = 1
+	RELEASED       = 2
+	READ_HELD      = 3
+	HELD           = WRITE_HELD
+)
+
+// Constants which represent the lock modes of a request.
+const (
+	WRITE int32 = 0 // WRITE is an exclusive request.
+	READ  int32 = 1 // READ is a shared request, which only conflicts with WRITE requests.
+)
+
+// Constants which represent the supported mutual exclusion algorithms.
+const (
+	RICART_AGRAWALA = "ricart-agrawala"
+	MAEKAWA         = "maekawa"
+	SUZUKI_KASAMI   = "suzuki-kasami"
+	RAYMOND         = "raymond"
+	CENTRALIZED     = "centralized"
+	LAMPORT         = "lamport"
+)
+
+// ErrNotEnoughReplies is returned by Lock when one or more peers could not be reached.
+var ErrNotEnoughReplies = errors.New("dme: did not get a reply from every peer")
+
+// ErrBusy is returned by Leave when the node holds or waits for a resource.
+var ErrBusy = errors.New("dme: the node holds or waits for a lock")
+
+// ErrMembership is returned by Leave, AddPeer and RemovePeer when the algorithm of the cluster does not support nodes joining and leaving.
+var ErrMembership = errors.New("dme: the algorithm does not support joining and leaving nodes")
+
+// ErrNoQueue is returned by Queue when the algorithm of the cluster has no wait queue to query. Only CENTRALIZED has one.
+var ErrNoQueue = errors.New("dme: the algorithm has no wait queue to query")
+
+// ErrTimeout is returned by Lock and RLock when their context is done, or Config.Timeout has passed, before the lock is held.
+// The request has then been withdrawn. The returned error also wraps the error of the context.
+var ErrTimeout = errors.New("dme: timed out waiting for the lock")
+
+// A timeoutError is ErrTimeout with the error of the context which ended the request.
+type timeoutError struct {
+	cause error // cause is the error of the context, context.DeadlineExceeded or context.Canceled.
+}
+
+func (e *timeoutError) Error() string {
+	return ErrTimeout.Error() + ": " + e.cause.Error()
+}
+
+func (e *timeoutError) Is(target error) bool {
+	return target == ErrTimeout
+}
+
+func (e *timeoutError) Unwrap() error {
+	return e.cause
+}
+
+// A Config describes a single node of a cluster.
+type Config struct {
+	Name        string               // Name is the unique name of the node.
+	Address     string               // Address is the address of the node.
+	Port        int                  // Port is the server port of the node.
+	Algorithm   string               // Algorithm is the mutual exclusion algorithm of the whole cluster. Defaults to RICART_AGRAWALA.
+	K           int                  // K is the number of nodes which may hold a resource at the same time (k-mutual exclusion). Only RICART_AGRAWALA supports K > 1. Defaults to 1.
+	Epoch       uint32               // Epoch is the epoch of the fences of the whole cluster. Raise it whenever the cluster is restarted, so new fences are greater than old ones.
+	Lease       Lease                // Lease is the lease of every resource without its own lease. Only RICART_AGRAWALA supports leases.
+	Leases      map[string]Lease     // Leases are the leases of single resources, by name.
+	Timeout     time.Duration        // Timeout bounds the time Lock and RLock wait for the lock, on top of their context. 0 means no bound.
+	Detector    FailureDetector      // Detector is the failure detector of the node. RICART_AGRAWALA leaves DEAD peers out of its requests.
+	Gossip      Gossip               // Gossip is the gossip with which the node discovers members and removes failed ones. Only RICART_AGRAWALA supports gossip.
+	TLS         TLS                  // TLS are the certificates of the node for mutual TLS between the nodes. Without them the nodes talk in plaintext.
+	Secret      string               // Secret is the secret of the whole cluster, with which every RPC is signed and authenticated. Empty disables authentication.
+	Metrics     Metrics              // Metrics collects the statistics of the node, e.g. for Prometheus. Nil collects none.
+	Tracing     trace.TracerProvider // Tracing is the tracer provider which traces the requests of the node across the cluster. Nil traces nothing.
+	WAL         string               // WAL is the path to the write-ahead log of the node, from which it recovers after a restart. Empty disables it. Only RICART_AGRAWALA supports it.
+	Coordinator string               // Coordinator is the name of the lock server of CENTRALIZED. If empty, the first node in name order is the coordinator.
+	Parent      string               // Parent is the node's parent in the tree of RAYMOND. The root is its own parent. If empty, the tree is built automatically.
+	Logger      *utils.Logger        // Logger logs all activities of the node.
+}
+
+// An algorithm is a distributed mutual exclusion algorithm running on a node.
+// It serves the RPCs it needs from the other nodes; all other RPCs are unimplemented.
+// An algorithm runs for a single resource and must release the instance's local semaphore when a request ends without the lock being held,
+// and when the lock is released again.
+type algorithm interface {
+	service.ServiceServer
+	lock(ctx context.Context) error   // lock blocks until the lock is held or ctx is done.
+	tryLock(ctx context.Context) bool // tryLock acquires the lock only if no other node holds or is granted it.
+	unlock()                          // unlock releases the lock.
+}
+
+// A watcher is an algorithm which reacts to changes in the health of peers.
+type watcher interface {
+	healthChanged(name string, health int) // healthChanged is called whenever the peer name becomes ALIVE, SUSPECTED or DEAD.
+}
+
+// A readWriter is an algorithm which also supports shared (READ) locks.
+// Resources of algorithms which do not support them take an exclusive lock instead.
+type readWriter interface {
+	rlock(ctx context.Context) error   // rlock blocks until the lock is held for reading or ctx is done.
+	tryRLock(ctx context.Context) bool // tryRLock acquires the lock for reading only if no other node holds or is granted it for writing.
+	runlock()                          // runlock releases the lock held for reading.
+}
+
+// A queuer is an algorithm with a single wait queue, which any node can query.
+type queuer interface {
+	waiting(ctx context.Context) (string, []string, error) // waiting returns the holder of the lock, or "" if it is free, and the nodes waiting for it, in order.
+}
+
+// A Mutex is a distributed mutual exclusion lock shared between all nodes in a cluster.
+// Each Mutex is a single node running a gRPC server on its own ip address.
+// To join the cluster, call Start. To acquire and release the lock, call Lock and Unlock.
+// Independent named locks are acquired through Resource.
+// As Lock takes a context and returns an error, a Mutex is not a sync.Locker. Locker adapts it to one.
+type Mutex struct {
+	node   *node   // node is the node running the algorithm.
+	router *router // router runs an instance of the algorithm for each resource.
+}
+
+// Start the Mutex's server and connect to the other peers (nodes) at the given ip addresses.
+// An ip address without an address part (i.e. only a port) is resolved against DefaultAddress.
+func (m *Mutex) Start(ipAddresses []string) {
+	m.node.start(ipAddresses, m.router)
+}
+
+// Join starts the Mutex's server and joins the cluster of the node at the seed ip address, instead of Start.
+// The node learns the other members of the cluster from the seed, and every member adds it to its peers.
+// An ip address without an address part (i.e. only a port) is resolved against DefaultAddress.
+// Only RICART_AGRAWALA supports nodes joining and leaving.
+func (m *Mutex) Join(seed string) {
+	m.node.join(seed, m.router)
+}
+
+// Leave hands off everything the node owes its peers, tells every peer that it leaves the cluster, and stops the Mutex.
+// Leave returns ErrBusy, and does nothing, if the node holds or waits for a resource.
+// If the algorithm does not support leaving, it returns ErrMembership and does nothing.
+func (m *Mutex) Leave() error {
+	if m.node.algorithm != RICART_AGRAWALA {
+		return ErrMembership
+	}
+	if err := m.router.leave(); err != nil {
+		return err
+	}
+
+	m.node.leave()
+	return nil
+}
+
+// AddPeer adds the node at the given ip address to the cluster of a started node, as if it had joined, and returns its name.
+// Both nodes add each other to their peers. A node in WANTED asks the new peer for its permission.
+// If the algorithm does not support joining, it returns ErrMembership and does nothing.
+func (m *Mutex) AddPeer(ctx context.Context, ipAddress string) (string, error) {
+	if m.node.algorithm != RICART_AGRAWALA {
+		return "", ErrMembership
+	}
+	if !strings.Contains(ipAddress, ":") {
+		ipAddress = DefaultAddress + ":" + ipAddress
+	}
+
+	return m.node.connect(ctx, ipAddress)
+}
+
+// RemovePeer removes the peer name from the node's peers, as if it had left the cluster, and reports whether it was a peer.
+// The node no longer asks it for its permission. The peer itself is not told.
+// If the algorithm does not support leaving, it returns ErrMembership and does nothing.
+func (m *Mutex) RemovePeer(name string) (bool, error) {
+	if m.node.algorithm != RICART_AGRAWALA {
+		return false, ErrMembership
+	}
+
+	m.node.gossiper.dead(name)
+	return m.node.bury(name), nil
+}
+
+// Stop shutdowns the Mutex's server.
+func (m *Mutex) Stop() {
+	m.node.stop()
+}
+
+// Name returns the unique name of the node.
+func (m *Mutex) Name() string {
+	return m.node.name
+}
+
+// Members returns the sorted names of all nodes in the cluster, as known to the node, including the node itself.
+func (m *Mutex) Members() []string {
+	return m.node.members()
+}
+
+// Health returns the health of the peer name, as seen by the failure detector: ALIVE, SUSPECTED or DEAD.
+// Without a failure detector, every peer is ALIVE.
+func (m *Mutex) Health(name string) int {
+	return m.node.detector.healthOf(name)
+}
+
+// Resource returns the named resource of the cluster.
+// Contention on one resource never blocks another.
+func (m *Mutex) Resource(name string) *Resource {
+	return m.router.resource(name)
+}
+
+// Lock blocks until the DefaultResource is held by this node, or until ctx is done.
+func (m *Mutex) Lock(ctx context.Context) error {
+	return m.Resource(DefaultResource).Lock(ctx)
+}
+
+// TryLock tries to acquire the DefaultResource without waiting for other nodes to leave the critical section.
+// It reports whether the DefaultResource is now held.
+func (m *Mutex) TryLock(ctx context.Context) bool {
+	return m.Resource(DefaultResource).TryLock(ctx)
+}
+
+// Unlock releases the DefaultResource.
+func (m *Mutex) Unlock() {
+	m.Resource(DefaultResource).Unlock()
+}
+
+// RLock blocks until the DefaultResource is held for reading by this node, or until ctx is done.
+func (m *Mutex) RLock(ctx context.Context) error {
+	return m.Resource(DefaultResource).RLock(ctx)
+}
+
+// TryRLock tries to acquire the DefaultResource for reading without waiting for other nodes to stop writing.
+// It reports whether the DefaultResource is now held for reading.
+func (m *Mutex) TryRLock(ctx context.Context) bool {
+	return m.Resource(DefaultResource).TryRLock(ctx)
+}
+
+// RUnlock releases the DefaultResource held for reading.
+func (m *Mutex) RUnlock() {
+	m.Resource(DefaultResource).RUnlock()
+}
+
+// Fence returns the fencing token of the node's latest entry into the DefaultResource,
+// or ErrNoFence if the algorithm of the cluster does not produce fences.
+func (m *Mutex) Fence() (Fence, error) {
+	return m.Resource(DefaultResource).Fence()
+}
+
+// Queue returns the node holding the DefaultResource, or "" if it is free, and the nodes waiting for it, in order,
+// or ErrNoQueue if the algorithm of the cluster has no wait queue.
+func (m *Mutex) Queue(ctx context.Context) (string, []string, error) {
+	return m.Resource(DefaultResource).Queue(ctx)
+}
+
+// Locker returns a sync.Locker interface that implements the Lock and Unlock methods by calling m.Lock and m.Unlock.
+// Its Lock waits without a deadline, and exits the program if the lock cannot be acquired, e.g. after Config.Timeout.
+func (m *Mutex) Locker() sync.Locker {
+	return m.Resource(DefaultResource).Locker()
+}
+
+// NewMutex creates a new Mutex for the node described by config.
+func NewMutex(config Config) *Mutex {
+	if config.Algorithm == "" {
+		config.Algorithm = RICART_AGRAWALA
+	}
+	if config.K == 0 {
+		config.K = 1
+	}
+	if config.K < 0 || (config.K > 1 && config.Algorithm != RICART_AGRAWALA) {
+		config.Logger.ErrorFatalf("%v does not support k = %v.", config.Algorithm, config.K)
+	}
+	if (config.Lease.TTL != 0 || len(config.Leases) > 0) && config.Algorithm != RICART_AGRAWALA {
+		config.Logger.ErrorFatalf("%v does not support leases.", config.Algorithm)
+	}
+	if config.Gossip.Interval != 0 && config.Algorithm != RICART_AGRAWALA {
+		config.Logger.ErrorFatalf("%v does not support gossip.", config.Algorithm)
+	}
+	if config.WAL != "" && config.Algorithm != RICART_AGRAWALA {
+		config.Logger.ErrorFatalf("%v does not support a write-ahead log.", config.Algorithm)
+	}
+	n := newNode(config.Name, config.Address, config.Port, config.Algorithm, config.K, config.Epoch, config.Detector, config.Gossip, config.WAL, config.TLS, config.Secret, config.Metrics, config.Tracing, config.Logger)
+
+	var newAlgorithm func(i *instance) algorithm
+	switch config.Algorithm {
+	case RICART_AGRAWALA:
+		newAlgorithm = func(i *instance) algorithm { return newRicartAgrawala(i) }
+	case MAEKAWA:
+		newAlgorithm = func(i *instance) algorithm { return newMaekawa(i) }
+	case SUZUKI_KASAMI:
+		newAlgorithm = func(i *instance) algorithm { return newSuzukiKasami(i) }
+	case RAYMOND:
+		newAlgorithm = func(i *instance) algorithm { return newRaymond(i, config.Parent) }
+	case CENTRALIZED:
+		newAlgorithm = func(i *instance) algorithm { return newCentralized(i, config.Coordinator) }
+	case LAMPORT:
+		newAlgorithm = func(i *instance) algorithm { return newLamportQueue(i) }
+	default:
+		config.Logger.ErrorFatalf("Unknown algorithm %v.", config.Algorithm)
+	}
+
+	return &Mutex{
+		node:   n,
+		router: newRouter(n, newAlgorithm, config.Lease, config.Leases, config.Timeout),
+	}
+}
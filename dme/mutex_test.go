@@ -0,0 +1,253 @@
+package dme
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"mandatory-exercise-2/utils"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testTimeout bounds every Lock of the tests, so a deadlock fails the test instead of hanging it.
+const testTimeout = 30 * time.Second
+
+// The adapters returned by Locker and RLocker are sync.Lockers, unlike Mutex and Resource themselves.
+var (
+	_ sync.Locker = (*locker)(nil)
+	_ sync.Locker = (*rlocker)(nil)
+)
+
+// freePort returns a port on 127.0.0.1 which is free at the time of the call.
+func freePort(t *testing.T) int {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	return l.Addr().(*net.TCPAddr).Port
+}
+
+// testLogger returns a Logger for the node name which writes no log file.
+func testLogger(name string) *utils.Logger {
+	discard := log.New(io.Discard, name+" ", log.LstdFlags)
+	logger := &utils.Logger{InfoLogger: discard, WarningLogger: discard, ErrorLogger: discard}
+	logger.SetLevel(utils.ERROR)
+	return logger
+}
+
+// newTestMutex creates the node name of config on the given port.
+func newTestMutex(t *testing.T, config Config, name string, port int) *Mutex {
+	t.Helper()
+
+	config.Name, config.Address, config.Port, config.Logger = name, "127.0.0.1", port, testLogger(name)
+	return NewMutex(config)
+}
+
+// newCluster starts n nodes d0, d1, ... of config connected to each other, and stops them when the test ends.
+func newCluster(t *testing.T, n int, config Config) []*Mutex {
+	t.Helper()
+
+	ports := make([]int, n)
+	ms := make([]*Mutex, n)
+	for i := range ms {
+		ports[i] = freePort(t)
+		ms[i] = newTestMutex(t, config, fmt.Sprintf("d%d", i), ports[i])
+	}
+
+	var wg sync.WaitGroup
+	for i, m := range ms {
+		var peers []string
+		for j, port := range ports {
+			if j != i {
+				peers = append(peers, fmt.Sprint(port))
+			}
+		}
+
+		wg.Add(1)
+		go func(m *Mutex, peers []string) {
+			defer wg.Done()
+			m.Start(peers)
+		}(m, peers)
+	}
+	wg.Wait()
+
+	t.Cleanup(func() {
+		for _, m := range ms {
+			m.Stop()
+		}
+	})
+	return ms
+}
+
+// eventually waits up to timeout for cond to hold, and fails the test with the message of format and v if it does not.
+func eventually(t *testing.T, timeout time.Duration, cond func() bool, format string, v ...interface{}) {
+	t.Helper()
+
+	for deadline := time.Now().Add(timeout); !cond(); time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf(format, v...)
+		}
+	}
+}
+
+// contend makes every node enter the critical section rounds times at the same time as the others,
+// and fails the test if two nodes ever hold it at the same time.
+func contend(t *testing.T, ms []*Mutex, rounds int) {
+	t.Helper()
+
+	contendK(t, ms, rounds, 1)
+}
+
+// contendK makes every node enter the critical section rounds times at the same time as the others,
+// fails the test if more than k nodes ever hold it at the same time, and returns the most nodes which held it at the same time.
+func contendK(t *testing.T, ms []*Mutex, rounds int, k int32) int32 {
+	t.Helper()
+
+	var holders, most int32
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	for _, m := range ms {
+		wg.Add(1)
+		go func(m *Mutex) {
+			defer wg.Done()
+			<-start
+			for round := 0; round < rounds; round++ {
+				ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+				err := m.Lock(ctx)
+				cancel()
+				if err != nil {
+					t.Errorf("%v could not lock in round %v: %v", m.Name(), round, err)
+					return
+				}
+
+				h := atomic.AddInt32(&holders, 1)
+				if h > k {
+					t.Errorf("%v holds the lock together with %v other node(s) in round %v", m.Name(), h-1, round)
+				}
+				for prev := atomic.LoadInt32(&most); h > prev; prev = atomic.LoadInt32(&most) {
+					if atomic.CompareAndSwapInt32(&most, prev, h) {
+						break
+					}
+				}
+				time.Sleep(time.Millisecond)
+				atomic.AddInt32(&holders, -1)
+				m.Unlock()
+			}
+		}(m)
+	}
+
+	close(start)
+	wg.Wait()
+	return most
+}
+
+// TestConcurrentRequesters checks that two nodes requesting the critical section at the same time never both hold it,
+// as each defers the other's request by the timestamp of its own outstanding request.
+func TestConcurrentRequesters(t *testing.T) {
+	contend(t, newCluster(t, 2, Config{}), 50)
+}
+
+// TestMutualExclusion checks that no two nodes of a cluster hold the critical section at the same time, with every algorithm.
+func TestMutualExclusion(t *testing.T) {
+	for _, algorithm := range []string{RICART_AGRAWALA, MAEKAWA, SUZUKI_KASAMI, RAYMOND, CENTRALIZED, LAMPORT} {
+		t.Run(algorithm, func(t *testing.T) {
+			contend(t, newCluster(t, 4, Config{Algorithm: algorithm}), 10)
+		})
+	}
+}
+
+// TestReadersWriters checks that readers hold the critical section together, and that a writer never holds it together with another node.
+func TestReadersWriters(t *testing.T) {
+	ms := newCluster(t, 4, Config{})
+
+	var readers, writers, mostReaders int32
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	for i, m := range ms {
+		wg.Add(1)
+		go func(m *Mutex, read bool) {
+			defer wg.Done()
+			<-start
+			for round := 0; round < 10; round++ {
+				ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+				var err error
+				if read {
+					err = m.RLock(ctx)
+				} else {
+					err = m.Lock(ctx)
+				}
+				cancel()
+				if err != nil {
+					t.Errorf("%v could not lock in round %v: %v", m.Name(), round, err)
+					return
+				}
+
+				if read {
+					r := atomic.AddInt32(&readers, 1)
+					for prev := atomic.LoadInt32(&mostReaders); r > prev; prev = atomic.LoadInt32(&mostReaders) {
+						if atomic.CompareAndSwapInt32(&mostReaders, prev, r) {
+							break
+						}
+					}
+					if w := atomic.LoadInt32(&writers); w > 0 {
+						t.Errorf("%v reads while %v node(s) write in round %v", m.Name(), w, round)
+					}
+					time.Sleep(10 * time.Millisecond)
+					atomic.AddInt32(&readers, -1)
+					m.RUnlock()
+				} else {
+					w := atomic.AddInt32(&writers, 1)
+					if r := atomic.LoadInt32(&readers); w > 1 || r > 0 {
+						t.Errorf("%v writes together with %v writer(s) and %v reader(s) in round %v", m.Name(), w-1, r, round)
+					}
+					time.Sleep(time.Millisecond)
+					atomic.AddInt32(&writers, -1)
+					m.Unlock()
+				}
+			}
+		}(m, i < 2)
+	}
+
+	close(start)
+	wg.Wait()
+
+	if mostReaders < 2 {
+		t.Error("the readers never held the critical section together")
+	}
+}
+
+// TestKMutualExclusion checks that at most k nodes hold the critical section at the same time, and that k nodes do.
+func TestKMutualExclusion(t *testing.T) {
+	const k = 2
+	if most := contendK(t, newCluster(t, 4, Config{K: k}), 20, k); most != k {
+		t.Errorf("at most %v node(s) held the critical section at the same time, want %v", most, k)
+	}
+}
+
+// TestLocker checks that the sync.Locker of a Mutex acquires and releases its lock.
+func TestLocker(t *testing.T) {
+	ms := newCluster(t, 2, Config{})
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	var l sync.Locker = ms[0].Locker()
+	l.Lock()
+	if ms[1].TryLock(ctx) {
+		t.Error("d1 acquired the lock while d0 held it through its Locker")
+		ms[1].Unlock()
+	}
+	l.Unlock()
+
+	if err := ms[1].Lock(ctx); err != nil {
+		t.Fatalf("d1 could not lock after d0 unlocked its Locker: %v", err)
+	}
+	ms[1].Unlock()
+}
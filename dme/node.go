@@ -0,0 +1,255 @@
+package dme
+
+import (
+	"context"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+	"mandatory-exercise-2/client"
+	"mandatory-exercise-2/server"
+	"mandatory-exercise-2/service"
+	"mandatory-exercise-2/utils"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// A node is a single process running on an ip address.
+// It can communicate with other nodes and is shared by all algorithms.
+type node struct {
+	name        string                           // name is the id of the node.
+	algorithm   string                           // algorithm is the mutual exclusion algorithm of the node, which must match its peers'.
+	k           int                              // k is the number of nodes which may hold a lock at the same time, which must match its peers'.
+	epoch       uint32                           // epoch is the epoch of the node's fences, which must match its peers'.
+	ipAddress   *net.TCPAddr                     // ipAddress is the full ip address of the node.
+	server      *server.Server                   // server is the internal server.Server of the node.
+	logger      *utils.Logger                    // logger is a log which logs all activities of the node.
+	peers       *peerSet                         // peers is the set of all the other nodes in the cluster.
+	joining     sync.Mutex                       // joining serializes nodes joining and leaving through this node.
+	changed     func(name string, joined bool)   // changed is called whenever a peer joins or leaves the cluster.
+	ready       chan struct{}                    // ready is closed when the node has connected to all its peers.
+	done        chan struct{}                    // done is closed when the node stops.
+	detector    *detector                        // detector is the failure detector of the node's peers.
+	gossiper    *gossiper                        // gossiper is the gossip membership of the node.
+	wal         *wal                             // wal is the write-ahead log of the node.
+	restore     func()                           // restore is called when the node has connected to its peers, before it is ready.
+	credentials credentials.TransportCredentials // credentials are the TLS credentials of the node, or nil if it does not use TLS.
+	secret      []byte                           // secret is the secret of the cluster with which every RPC is signed, or nil if RPCs are not signed.
+	metrics     Metrics                          // metrics collects the statistics of the node.
+	tracing     trace.TracerProvider             // tracing is the tracer provider of the node, or nil if it does not trace.
+	tracer      trace.Tracer                     // tracer traces the requests of the node.
+	service.UnimplementedServiceServer
+}
+
+// start the node's server for the algorithm and connect to other peers (nodes).
+func (n *node) start(ipAddresses []string, algorithm service.ServiceServer) {
+	n.logger.WarningPrintln("STARTING NODE...")
+	n.server.Start(n.ipAddress.String(), algorithm)
+	n.logger.WarningPrintln("NODE STARTED.")
+
+	for _, ipAddress := range ipAddresses {
+		address := ipAddress
+		if !strings.Contains(ipAddress, ":") {
+			address = DefaultAddress + ":" + address
+		}
+
+		ip, err := net.ResolveTCPAddr("tcp", address)
+		if err != nil {
+			n.logger.ErrorPrintf("Invalid ip address %v. Skipping", address)
+			continue
+		}
+
+		if ip.String() == n.ipAddress.String() {
+			n.logger.WarningPrintf("Trying to connect to self (%v). Skipping!", address)
+			continue
+		}
+
+		n.registerPeer(address)
+	}
+
+	n.metrics.Peers(n.peers.len())
+	n.restore()
+	close(n.ready)
+
+	if n.detector.enabled() {
+		go n.detect()
+	}
+	if n.gossiper.enabled() {
+		go n.gossip()
+	}
+}
+
+// stop shutdowns the node.
+func (n *node) stop() {
+	n.logger.WarningPrintln("STOPPING NODE...")
+	n.server.Stop()
+	close(n.done)
+	close(n.detector.done)
+	close(n.gossiper.done)
+	n.logger.WarningPrintln("NODE STOPPED.")
+}
+
+// registerPeer connects to and registers another node on this node at the specified port.
+// A peer running another algorithm, another k or another epoch is refused, and so is a peer without a certificate for its name if the node uses TLS.
+func (n *node) registerPeer(ipAddress string) {
+	peer := n.dial(ipAddress)
+	from, certified := n.certified()
+	info, err := peer.GetName(context.Background(), &service.NameRequest{Name: n.name, Algorithm: n.algorithm, K: int32(n.k), Epoch: n.epoch}, from)
+	if err != nil {
+		n.logger.ErrorFatalf("Could not fetch name of peer. :: %v", err)
+	}
+
+	if err := certified(info.Name); err != nil {
+		n.logger.ErrorFatalf("Peer at %v is not certified as %v. Refusing to peer. :: %v", ipAddress, info.Name, err)
+	}
+
+	if info.Algorithm != n.algorithm {
+		n.logger.ErrorFatalf("Peer %v runs %v, but %v runs %v. Refusing to peer.", info.Name, info.Algorithm, n.name, n.algorithm)
+	}
+
+	if int(info.K) != n.k {
+		n.logger.ErrorFatalf("Peer %v allows %v holders, but %v allows %v. Refusing to peer.", info.Name, info.K, n.name, n.k)
+	}
+
+	if info.Epoch != n.epoch {
+		n.logger.ErrorFatalf("Peer %v is in epoch %v, but %v is in epoch %v. Refusing to peer.", info.Name, info.Epoch, n.name, n.epoch)
+	}
+
+	n.peers.add(info.Name, ipAddress, peer)
+}
+
+// dial connects to the node at the given ip address with the node's dialOptions.
+func (n *node) dial(ipAddress string) service.ServiceClient {
+	return client.NewClient(ipAddress, n.logger, n.dialOptions()...)
+}
+
+// dialOptions returns the options with which the node connects to its peers: over TLS if the node uses it,
+// signing every RPC if the cluster has a secret, and sending the trace context along if the node traces.
+func (n *node) dialOptions() []grpc.DialOption {
+	opts := []grpc.DialOption{grpc.WithInsecure()}
+	if n.credentials != nil {
+		opts = []grpc.DialOption{grpc.WithTransportCredentials(n.credentials)}
+	}
+	if n.secret != nil {
+		opts = append(opts, client.WithSecret(n.secret))
+	}
+	if n.tracing != nil {
+		opts = append(opts, client.WithTracing())
+	}
+	return opts
+}
+
+// members returns the sorted names of all nodes in the cluster, including this node.
+func (n *node) members() []string {
+	names := append(n.peers.names(), n.name)
+	sort.Strings(names)
+	return names
+}
+
+// GetName returns an info struct to the caller.
+// A caller running another algorithm, another k or another epoch is refused, and so is a caller without a certificate for its name if the node uses TLS.
+func (n *node) GetName(ctx context.Context, nq *service.NameRequest) (*service.NameReply, error) {
+	n.logger.InfoPrintf("%v is requesting the name of %v.\n", nq.Name, n.name)
+
+	if err := n.authenticate(ctx, nq.Name); err != nil {
+		return nil, err
+	}
+	if err := n.admit(nq); err != nil {
+		return nil, err
+	}
+
+	n.logger.InfoPrintf("Sending back %v.\n", n.name)
+	return &service.NameReply{Name: n.name, Algorithm: n.algorithm, K: int32(n.k), Epoch: n.epoch}, nil
+}
+
+// admit returns an error if the node nq runs another algorithm, another k or another epoch, and may not be a peer.
+func (n *node) admit(nq *service.NameRequest) error {
+	if nq.Algorithm != n.algorithm {
+		n.logger.WarningPrintf("%v runs %v, but %v runs %v. Refusing to peer.", nq.Name, nq.Algorithm, n.name, n.algorithm)
+		return status.Errorf(codes.FailedPrecondition, "%v runs %v, not %v", n.name, n.algorithm, nq.Algorithm)
+	}
+
+	if int(nq.K) != n.k {
+		n.logger.WarningPrintf("%v allows %v holders, but %v allows %v. Refusing to peer.", nq.Name, nq.K, n.name, n.k)
+		return status.Errorf(codes.FailedPrecondition, "%v allows %v holders, not %v", n.name, n.k, nq.K)
+	}
+
+	if nq.Epoch != n.epoch {
+		n.logger.WarningPrintf("%v is in epoch %v, but %v is in epoch %v. Refusing to peer.", nq.Name, nq.Epoch, n.name, n.epoch)
+		return status.Errorf(codes.FailedPrecondition, "%v is in epoch %v, not %v", n.name, n.epoch, nq.Epoch)
+	}
+
+	return nil
+}
+
+// createIpAddress converts an address string and a port (integer) to a string.
+func createIpAddress(address string, port int) string {
+	return address + ":" + strconv.Itoa(port)
+}
+
+// newNode creates a new node with the specified unique name, ip address, algorithm, k, epoch, failure detector, gossip,
+// write-ahead log, which is replayed, TLS certificates, secret, and metrics and tracer provider, which default to none.
+func newNode(name string, address string, serverPort int, algorithm string, k int, epoch uint32, fd FailureDetector, g Gossip, walPath string, t TLS, secret string, metrics Metrics, tp trace.TracerProvider, logger *utils.Logger) *node {
+	logger.WarningPrintf("CREATING NODE WITH ID '%v' AND IP ADDRESS '%v:%v'", name, address, serverPort)
+
+	ipAddress, err := net.ResolveTCPAddr("tcp", createIpAddress(address, serverPort))
+	if err != nil {
+		logger.ErrorFatalf("Error resolving tcp address %v:%v. :: %v", address, serverPort, err)
+	}
+
+	var creds credentials.TransportCredentials
+	var opts []grpc.ServerOption
+	if t.enabled() {
+		if creds, err = t.credentials(); err != nil {
+			logger.ErrorFatalf("Could not load the TLS certificates of %v. :: %v", name, err)
+		}
+		opts = append(opts, grpc.Creds(creds))
+		logger.WarningPrintf("%v USES MUTUAL TLS.", name)
+	}
+
+	if metrics == nil {
+		metrics = noMetrics{}
+	}
+
+	// The secret is checked before an RPC is traced, so rejected RPCs leave no spans.
+	var key []byte
+	if secret != "" {
+		key = []byte(secret)
+		opts = append(opts, server.WithSecret(key, ipAddress.String(), logger))
+		logger.WarningPrintf("%v SIGNS AND AUTHENTICATES EVERY RPC.", name)
+	}
+
+	tracer := noop.NewTracerProvider().Tracer("")
+	if tp != nil {
+		tracer = tp.Tracer("mandatory-exercise-2/dme")
+		opts = append(opts, server.WithTracing(tp))
+	}
+
+	return &node{
+		name:        name,
+		algorithm:   algorithm,
+		k:           k,
+		epoch:       epoch,
+		ipAddress:   ipAddress,
+		server:      server.NewServer(logger, opts...),
+		logger:      logger,
+		peers:       newPeerSet(),
+		changed:     func(string, bool) {},
+		restore:     func() {},
+		ready:       make(chan struct{}),
+		done:        make(chan struct{}),
+		detector:    newDetector(fd),
+		gossiper:    newGossiper(g),
+		wal:         openWAL(walPath, logger),
+		credentials: creds,
+		secret:      key,
+		metrics:     metrics,
+		tracing:     tp,
+		tracer:      tracer,
+	}
+}
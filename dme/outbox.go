@@ -0,0 +1,48 @@
+package dme
+
+import "sync"
+
+// An outbox sends messages to a single node one at a time, in the order they were posted.
+// Posting never blocks, so messages can be sent while holding a lock without risking a distributed deadlock.
+type outbox struct {
+	pending []func()      // pending are the messages not sent yet.
+	signal  chan struct{} // signal wakes up the sending go routine.
+	mu      sync.Mutex
+}
+
+// post adds a message to the back of the outbox.
+func (o *outbox) post(send func()) {
+	o.mu.Lock()
+	o.pending = append(o.pending, send)
+	o.mu.Unlock()
+
+	select {
+	case o.signal <- struct{}{}:
+	default:
+	}
+}
+
+// run sends the pending messages of the outbox forever.
+func (o *outbox) run() {
+	for range o.signal {
+		for {
+			o.mu.Lock()
+			if len(o.pending) == 0 {
+				o.mu.Unlock()
+				break
+			}
+			send := o.pending[0]
+			o.pending = o.pending[1:]
+			o.mu.Unlock()
+
+			send()
+		}
+	}
+}
+
+// newOutbox creates and returns a new empty outbox, which sends its messages on a new go routine.
+func newOutbox() *outbox {
+	o := &outbox{signal: make(chan struct{}, 1)}
+	go o.run()
+	return o
+}
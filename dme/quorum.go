@@ -0,0 +1,34 @@
+package dme
+
+import "math"
+
+// gridQuorum returns the voting set of the member name.
+// The sorted members are laid out row by row in a grid ⌈√N⌉ columns wide,
+// and the voting set of a member is every member in its row and its column.
+// If the last row is incomplete, two voting sets still intersect:
+// a member in the last row shares a member with any other row through its own column,
+// since all rows but the last are complete.
+func gridQuorum(members []string, name string) []string {
+	width := int(math.Ceil(math.Sqrt(float64(len(members)))))
+
+	index := -1
+	for i, member := range members {
+		if member == name {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return nil
+	}
+
+	row, column := index/width, index%width
+	quorum := make([]string, 0, 2*width-1)
+	for i, member := range members {
+		if i/width == row || i%width == column {
+			quorum = append(quorum, member)
+		}
+	}
+
+	return quorum
+}
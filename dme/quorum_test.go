@@ -0,0 +1,53 @@
+package dme
+
+import (
+	"fmt"
+	"testing"
+)
+
+// TestGridQuorumIntersection checks that every two voting sets intersect, and contain their own member,
+// for complete and incomplete grids alike.
+func TestGridQuorumIntersection(t *testing.T) {
+	for n := 1; n <= 30; n++ {
+		members := make([]string, n)
+		for i := range members {
+			members[i] = fmt.Sprintf("node%02d", i)
+		}
+
+		quorums := make([]map[string]bool, n)
+		for i, member := range members {
+			quorums[i] = make(map[string]bool)
+			for _, voter := range gridQuorum(members, member) {
+				quorums[i][voter] = true
+			}
+			if !quorums[i][member] {
+				t.Errorf("N = %v: the voting set of %v does not contain %v", n, member, member)
+			}
+		}
+
+		for i := range members {
+			for j := i + 1; j < n; j++ {
+				if !intersect(quorums[i], quorums[j]) {
+					t.Errorf("N = %v: the voting sets of %v and %v do not intersect", n, members[i], members[j])
+				}
+			}
+		}
+	}
+}
+
+// TestGridQuorumUnknownMember checks that a name which is not a member has no voting set.
+func TestGridQuorumUnknownMember(t *testing.T) {
+	if quorum := gridQuorum([]string{"node0", "node1", "node2"}, "node3"); quorum != nil {
+		t.Errorf("the voting set of a non-member is %v, not nil", quorum)
+	}
+}
+
+// intersect reports whether the sets a and b have a member in common.
+func intersect(a map[string]bool, b map[string]bool) bool {
+	for member := range a {
+		if b[member] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,216 @@
+package dme
+
+import (
+	"context"
+	"mandatory-exercise-2/service"
+	"mandatory-exercise-2/utils"
+	"sync"
+)
+
+// raymond is Raymond's tree based token algorithm.
+// The nodes form a tree, and every node points (holder) towards the node holding the token.
+// Requests travel up the tree to the token and the token travels back down, so an entry costs O(log N) messages in a balanced tree.
+type raymond struct {
+	*instance
+	parent   string             // parent is the node's parent in the tree. Empty if the tree is built automatically.
+	mu       sync.Mutex         // mu guards all the fields below.
+	holder   string             // holder is the neighbour in the direction of the token, or the node itself if it holds the token.
+	using    bool               // using is true while the node is in HELD.
+	asked    bool               // asked is true if the node has sent a request to holder which has not been answered yet.
+	queue    *utils.Queue       // queue is the node itself and the neighbours waiting for the token, in the order they asked.
+	acquired chan struct{}      // acquired is closed when the node enters HELD.
+	outboxes map[string]*outbox // outboxes are the outboxes to the neighbours.
+	once     sync.Once
+}
+
+// lock enqueues the node's own request and blocks until the token reaches it, or until ctx is done.
+// If ctx is done first, the request is taken out of the queue and the token is passed on if it arrives.
+func (ry *raymond) lock(ctx context.Context) error {
+	ry.setup()
+	ry.mu.Lock()
+	ry.logger.InfoPrintf("%v entered WANTED\n", ry.name)
+	ry.acquired = make(chan struct{})
+	acquired := ry.acquired
+	ry.queue.Enqueue(0, ry.name)
+	ry.assignPrivilege()
+	ry.makeRequest()
+	ry.mu.Unlock()
+
+	select {
+	case <-acquired:
+		return nil
+	case <-ctx.Done():
+	}
+
+	ry.mu.Lock()
+	if ry.using {
+		ry.mu.Unlock()
+		return nil
+	}
+	ry.queue.Remove(ry.name)
+	ry.logger.InfoPrintf("%v entered RELEASED\n", ry.name)
+	ry.mu.Unlock()
+	<-ry.local
+	return ctx.Err()
+}
+
+// tryLock enters HELD only if the node holds the token and nobody is waiting for it. No messages are sent.
+func (ry *raymond) tryLock(_ context.Context) bool {
+	ry.setup()
+	ry.mu.Lock()
+	defer ry.mu.Unlock()
+
+	if ry.holder != ry.name || ry.using || !ry.queue.IsEmpty() {
+		ry.logger.InfoPrintf("%v could not get the lock straight away.\n", ry.name)
+		<-ry.local
+		return false
+	}
+
+	ry.held()
+	return true
+}
+
+// unlock leaves HELD and passes the token on to the first waiting neighbour.
+func (ry *raymond) unlock() {
+	ry.mu.Lock()
+	defer ry.mu.Unlock()
+
+	if !ry.using {
+		ry.logger.WarningPrintf("%v tried to unlock without holding the lock.\n", ry.name)
+		return
+	}
+
+	ry.using = false
+	ry.logger.InfoPrintf("%v entered RELEASED\n", ry.name)
+	ry.assignPrivilege()
+	ry.makeRequest()
+	<-ry.local
+}
+
+// setup points the node's holder at its parent once the node has connected to all its peers.
+// Without a configured parent, the members are laid out as a binary heap in name order and the first member holds the token.
+func (ry *raymond) setup() {
+	<-ry.ready
+	ry.once.Do(func() {
+		ry.mu.Lock()
+		defer ry.mu.Unlock()
+
+		ry.holder = ry.parent
+		if ry.holder == "" {
+			members := ry.members()
+			for i, member := range members {
+				if member != ry.name {
+					continue
+				}
+
+				if i == 0 {
+					ry.holder = ry.name
+				} else {
+					ry.holder = members[(i-1)/2]
+				}
+			}
+		}
+
+		if ry.holder == ry.name {
+			ry.logger.InfoPrintf("%v is the root and holds the initial token.\n", ry.name)
+		} else {
+			ry.logger.InfoPrintf("%v has the parent %v.\n", ry.name, ry.holder)
+		}
+	})
+}
+
+// held makes the node enter HELD. The mutex must be held.
+func (ry *raymond) held() {
+	ry.using = true
+	ry.logger.InfoPrintf("%v entered HELD\n", ry.name)
+	ry.logger.InfoPrintf("%v IS NOW IN THE CRITICAL SECTION!\n", ry.name)
+}
+
+// assignPrivilege gives an idle token to the first node in the queue, which may be the node itself. The mutex must be held.
+func (ry *raymond) assignPrivilege() {
+	if ry.holder != ry.name || ry.using || ry.queue.IsEmpty() {
+		return
+	}
+
+	_, next := ry.queue.Dequeue()
+	ry.asked = false
+
+	if next == ry.name {
+		ry.held()
+		close(ry.acquired)
+		return
+	}
+
+	ry.holder = next
+	ry.logger.InfoPrintf("%v is passing the token to %v.\n", ry.name, next)
+	ry.send(next, func(c service.ServiceClient) error {
+		_, err := c.PassToken(context.Background(), &service.Token{Name: ry.name, Resource: ry.resource})
+		return err
+	})
+}
+
+// makeRequest asks holder for the token if someone is waiting for it and the node has not asked already. The mutex must be held.
+func (ry *raymond) makeRequest() {
+	if ry.holder == ry.name || ry.queue.IsEmpty() || ry.asked {
+		return
+	}
+
+	ry.asked = true
+	holder := ry.holder
+	ry.logger.InfoPrintf("(Send) %v is requesting the token from %v.\n", ry.name, holder)
+	ry.send(holder, func(c service.ServiceClient) error {
+		_, err := c.Publish(context.Background(), &service.Request{Name: ry.name, Resource: ry.resource})
+		return err
+	})
+}
+
+// send posts a message to the neighbour to.
+func (ry *raymond) send(to string, message func(c service.ServiceClient) error) {
+	o, ok := ry.outboxes[to]
+	if !ok {
+		o = newOutbox()
+		ry.outboxes[to] = o
+	}
+
+	o.post(func() {
+		if err := message(ry.peers.get(to)); err != nil {
+			ry.logger.ErrorPrintf("Error sending message to %v. :: %v\n", to, err)
+		}
+	})
+}
+
+// Publish receives a request for the token from a neighbour.
+func (ry *raymond) Publish(_ context.Context, r *service.Request) (*service.Reply, error) {
+	ry.setup()
+	ry.mu.Lock()
+	defer ry.mu.Unlock()
+	ry.logger.InfoPrintf("(Receive) %v received a request for the token from %v.\n", ry.name, r.Name)
+
+	ry.queue.Enqueue(0, r.Name)
+	ry.assignPrivilege()
+	ry.makeRequest()
+	return &service.Reply{}, nil
+}
+
+// PassToken receives the token from a neighbour.
+func (ry *raymond) PassToken(_ context.Context, t *service.Token) (*service.Reply, error) {
+	ry.setup()
+	ry.mu.Lock()
+	defer ry.mu.Unlock()
+	ry.logger.InfoPrintf("%v received the token from %v.\n", ry.name, t.Name)
+
+	ry.holder = ry.name
+	ry.assignPrivilege()
+	ry.makeRequest()
+	return &service.Reply{}, nil
+}
+
+// newRaymond creates Raymond's algorithm for the instance i with the given parent in the tree.
+func newRaymond(i *instance, parent string) *raymond {
+	return &raymond{
+		instance: i,
+		parent:   parent,
+		queue:    utils.NewQueue(),
+		outboxes: make(map[string]*outbox),
+	}
+}
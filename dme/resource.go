@@ -0,0 +1,396 @@
+package dme
+
+import (
+	"context"
+	"mandatory-exercise-2/service"
+	"mandatory-exercise-2/utils"
+	"sync"
+	"time"
+)
+
+// DefaultResource is the name of the resource locked by the Lock, TryLock and Unlock methods of a Mutex.
+const DefaultResource = ""
+
+// A Lease bounds how long a node may keep other nodes waiting for a resource without renewing it.
+// A node deferring requests renews its lease at the requesters every Renew.
+// A requester treats a node whose lease has expired as released, so a hung holder does not block the cluster forever.
+type Lease struct {
+	TTL   time.Duration // TTL is the duration of the lease. 0 means the lease never expires.
+	Renew time.Duration // Renew is the interval between renewals. Defaults to a third of TTL.
+}
+
+// An instance is the part of a node belonging to a single resource.
+// Every resource runs its own instance of the algorithm, so contention on one resource never blocks another.
+type instance struct {
+	*node
+	resource string         // resource is the name of the resource.
+	lamport  *utils.Lamport // lamport is a logical clock.
+	local    chan struct{}  // local is a semaphore serializing local callers of Lock and TryLock.
+	lease    Lease          // lease is the lease of the resource.
+}
+
+// newInstance creates the instance of the node n for the named resource with the given lease.
+func newInstance(n *node, resource string, lease Lease) *instance {
+	if lease.Renew == 0 {
+		lease.Renew = lease.TTL / 3
+	}
+
+	return &instance{
+		node:     n,
+		resource: resource,
+		lease:    lease,
+		lamport:  utils.NewLamport(),
+		local:    make(chan struct{}, 1),
+	}
+}
+
+// A Resource is a named lock shared between all nodes in a cluster, such as a database table or a file.
+// Resources are created on first use, on every node.
+type Resource struct {
+	instance  *instance     // instance is the node's state of the resource.
+	algorithm algorithm     // algorithm is the algorithm instance running for the resource.
+	timeout   time.Duration // timeout bounds the time to wait for the resource, or 0 if it is unbounded.
+}
+
+// Name returns the name of the Resource.
+func (r *Resource) Name() string {
+	return r.instance.resource
+}
+
+// Lock blocks until the Resource is held by this node, or until ctx is done or the timeout of the Resource has passed.
+// The request is then withdrawn, and Lock returns ErrTimeout.
+func (r *Resource) Lock(ctx context.Context) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	select {
+	case r.instance.local <- struct{}{}:
+	case <-ctx.Done():
+		return &timeoutError{cause: ctx.Err()}
+	}
+
+	return r.timedOut(ctx, r.algorithm.lock(ctx))
+}
+
+// withTimeout returns ctx bounded by the timeout of the Resource.
+func (r *Resource) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if r.timeout == 0 {
+		return context.WithCancel(ctx)
+	}
+
+	return context.WithTimeout(ctx, r.timeout)
+}
+
+// timedOut returns ErrTimeout if the request ended with the error err because ctx is done, and err otherwise.
+func (r *Resource) timedOut(ctx context.Context, err error) error {
+	if err == nil || ctx.Err() == nil {
+		return err
+	}
+
+	r.instance.logger.WarningPrintf("%v gave up waiting for the lock %q. :: %v\n", r.instance.name, r.instance.resource, ctx.Err())
+	return &timeoutError{cause: ctx.Err()}
+}
+
+// TryLock tries to acquire the Resource without waiting for other nodes to leave the critical section.
+// It reports whether the Resource is now held.
+func (r *Resource) TryLock(ctx context.Context) bool {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	select {
+	case r.instance.local <- struct{}{}:
+	default:
+		return false
+	}
+
+	return r.algorithm.tryLock(ctx)
+}
+
+// Unlock releases the Resource.
+func (r *Resource) Unlock() {
+	r.algorithm.unlock()
+}
+
+// RLock blocks until the Resource is held for reading by this node, or until ctx is done or the timeout of the Resource has passed.
+// The request is then withdrawn, and RLock returns ErrTimeout.
+// Other nodes may hold the Resource for reading at the same time.
+// If the algorithm of the cluster does not support shared locks, RLock is the same as Lock.
+func (r *Resource) RLock(ctx context.Context) error {
+	rw, ok := r.algorithm.(readWriter)
+	if !ok {
+		return r.Lock(ctx)
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	select {
+	case r.instance.local <- struct{}{}:
+	case <-ctx.Done():
+		return &timeoutError{cause: ctx.Err()}
+	}
+
+	return r.timedOut(ctx, rw.rlock(ctx))
+}
+
+// TryRLock tries to acquire the Resource for reading without waiting for other nodes to stop writing.
+// It reports whether the Resource is now held for reading.
+func (r *Resource) TryRLock(ctx context.Context) bool {
+	rw, ok := r.algorithm.(readWriter)
+	if !ok {
+		return r.TryLock(ctx)
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	select {
+	case r.instance.local <- struct{}{}:
+	default:
+		return false
+	}
+
+	return rw.tryRLock(ctx)
+}
+
+// RUnlock releases the Resource held for reading.
+func (r *Resource) RUnlock() {
+	rw, ok := r.algorithm.(readWriter)
+	if !ok {
+		r.Unlock()
+		return
+	}
+
+	rw.runlock()
+}
+
+// Fence returns the fencing token of the node's latest entry into the Resource,
+// to be passed on to the systems the Resource protects. See Fence.
+// It returns 0 if the node has not entered yet, which FenceChecker.Check never accepts,
+// and ErrNoFence if the algorithm of the cluster does not produce fences.
+func (r *Resource) Fence() (Fence, error) {
+	f, ok := r.algorithm.(fencer)
+	if !ok {
+		return 0, ErrNoFence
+	}
+
+	return f.fence(), nil
+}
+
+// Queue returns the node holding the Resource, or "" if it is free, and the nodes waiting for it, in order,
+// as seen by the coordinator of CENTRALIZED. It returns ErrNoQueue if the algorithm of the cluster has no wait queue.
+func (r *Resource) Queue(ctx context.Context) (string, []string, error) {
+	q, ok := r.algorithm.(queuer)
+	if !ok {
+		return "", nil, ErrNoQueue
+	}
+
+	return q.waiting(ctx)
+}
+
+// RLocker returns a sync.Locker interface that implements the Lock and Unlock methods by calling r.RLock and r.RUnlock.
+func (r *Resource) RLocker() sync.Locker {
+	return (*rlocker)(r)
+}
+
+// rlocker adapts a Resource held for reading to the sync.Locker interface.
+type rlocker Resource
+
+func (l *rlocker) Lock() {
+	r := (*Resource)(l)
+	if err := r.RLock(context.Background()); err != nil {
+		r.instance.logger.ErrorFatalf("Could not acquire the lock %q for reading. :: %v", r.instance.resource, err)
+	}
+}
+
+func (l *rlocker) Unlock() {
+	(*Resource)(l).RUnlock()
+}
+
+// Locker returns a sync.Locker interface that implements the Lock and Unlock methods by calling r.Lock and r.Unlock.
+func (r *Resource) Locker() sync.Locker {
+	return (*locker)(r)
+}
+
+// locker adapts a Resource to the sync.Locker interface.
+type locker Resource
+
+func (l *locker) Lock() {
+	r := (*Resource)(l)
+	if err := r.Lock(context.Background()); err != nil {
+		r.instance.logger.ErrorFatalf("Could not acquire the lock %q. :: %v", r.instance.resource, err)
+	}
+}
+
+func (l *locker) Unlock() {
+	(*Resource)(l).Unlock()
+}
+
+// A router serves the RPCs of a node and passes each of them on to the algorithm of the resource it is for.
+type router struct {
+	*node
+	newAlgorithm func(i *instance) algorithm // newAlgorithm creates the algorithm for a new resource.
+	lease        Lease                       // lease is the lease of resources without their own lease.
+	leases       map[string]Lease            // leases are the leases of single resources, by name.
+	timeout      time.Duration               // timeout bounds the time to wait for every resource.
+	mu           sync.Mutex                  // mu guards resources.
+	resources    map[string]*Resource        // resources are the resources used so far, by name.
+}
+
+// resource returns the named resource, creating it on first use.
+func (rt *router) resource(name string) *Resource {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	r, ok := rt.resources[name]
+	if !ok {
+		lease, ok := rt.leases[name]
+		if !ok {
+			lease = rt.lease
+		}
+
+		i := newInstance(rt.node, name, lease)
+		r = &Resource{instance: i, algorithm: rt.newAlgorithm(i), timeout: rt.timeout}
+		rt.resources[name] = r
+		rt.logger.InfoPrintf("%v created the resource %q.\n", rt.name, name)
+	}
+
+	return r
+}
+
+func (rt *router) Publish(ctx context.Context, r *service.Request) (*service.Reply, error) {
+	return rt.resource(r.Resource).algorithm.Publish(ctx, r)
+}
+
+func (rt *router) ReplySender(ctx context.Context, r *service.Request) (*service.Reply, error) {
+	return rt.resource(r.Resource).algorithm.ReplySender(ctx, r)
+}
+
+func (rt *router) Request(ctx context.Context, r *service.Request) (*service.Reply, error) {
+	return rt.resource(r.Resource).algorithm.Request(ctx, r)
+}
+
+func (rt *router) Locked(ctx context.Context, r *service.Request) (*service.Reply, error) {
+	return rt.resource(r.Resource).algorithm.Locked(ctx, r)
+}
+
+func (rt *router) Failed(ctx context.Context, r *service.Request) (*service.Reply, error) {
+	return rt.resource(r.Resource).algorithm.Failed(ctx, r)
+}
+
+func (rt *router) Inquire(ctx context.Context, r *service.Request) (*service.Reply, error) {
+	return rt.resource(r.Resource).algorithm.Inquire(ctx, r)
+}
+
+func (rt *router) Relinquish(ctx context.Context, r *service.Request) (*service.Reply, error) {
+	return rt.resource(r.Resource).algorithm.Relinquish(ctx, r)
+}
+
+func (rt *router) Release(ctx context.Context, r *service.Request) (*service.Reply, error) {
+	return rt.resource(r.Resource).algorithm.Release(ctx, r)
+}
+
+func (rt *router) Renew(ctx context.Context, r *service.Request) (*service.Reply, error) {
+	return rt.resource(r.Resource).algorithm.Renew(ctx, r)
+}
+
+func (rt *router) Cancel(ctx context.Context, r *service.Request) (*service.Reply, error) {
+	return rt.resource(r.Resource).algorithm.Cancel(ctx, r)
+}
+
+func (rt *router) Recover(ctx context.Context, r *service.Request) (*service.Reply, error) {
+	return rt.resource(r.Resource).algorithm.Recover(ctx, r)
+}
+
+func (rt *router) PassToken(ctx context.Context, t *service.Token) (*service.Reply, error) {
+	return rt.resource(t.Resource).algorithm.PassToken(ctx, t)
+}
+
+func (rt *router) GetQueue(ctx context.Context, q *service.QueueRequest) (*service.QueueReply, error) {
+	return rt.resource(q.Resource).algorithm.GetQueue(ctx, q)
+}
+
+// healthChanged passes a change in the health of the peer name on to the algorithm of every resource which watches it.
+func (rt *router) healthChanged(name string, health int) {
+	rt.mu.Lock()
+	watchers := make([]watcher, 0, len(rt.resources))
+	for _, r := range rt.resources {
+		if w, ok := r.algorithm.(watcher); ok {
+			watchers = append(watchers, w)
+		}
+	}
+	rt.mu.Unlock()
+
+	for _, w := range watchers {
+		w.healthChanged(name, health)
+	}
+}
+
+// membershipChanged passes a peer joining or leaving the cluster on to the algorithm of every resource,
+// and reports the new number of peers to the metrics.
+func (rt *router) membershipChanged(name string, joined bool) {
+	rt.metrics.Peers(rt.peers.len())
+
+	rt.mu.Lock()
+	members := make([]member, 0, len(rt.resources))
+	for _, r := range rt.resources {
+		if m, ok := r.algorithm.(member); ok {
+			members = append(members, m)
+		}
+	}
+	rt.mu.Unlock()
+
+	for _, m := range members {
+		if joined {
+			m.joined(name)
+		} else {
+			m.left(name)
+		}
+	}
+}
+
+// leave hands off everything the algorithm of every resource owes the peers, before the node leaves the cluster.
+// It returns ErrBusy if the node holds or waits for a resource, and ErrMembership if the algorithm does not support leaving.
+func (rt *router) leave() error {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	for _, r := range rt.resources {
+		m, ok := r.algorithm.(member)
+		if !ok {
+			return ErrMembership
+		}
+		if err := m.leave(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// restore recovers the state of every resource recorded in the node's write-ahead log, before the node is ready.
+func (rt *router) restore() {
+	for name, s := range rt.wal.recovered() {
+		if r, ok := rt.resource(name).algorithm.(recoverer); ok {
+			r.recover(s)
+		}
+	}
+}
+
+// newRouter creates a router for the node n, which creates the algorithm of each resource with newAlgorithm.
+// A resource has the lease of leases with its name, or otherwise lease, and the given timeout.
+// The router passes the changes in the health of peers found by the node's failure detector on to the algorithms,
+// and recovers the resources recorded in the node's write-ahead log when the node starts.
+func newRouter(n *node, newAlgorithm func(i *instance) algorithm, lease Lease, leases map[string]Lease, timeout time.Duration) *router {
+	rt := &router{
+		node:         n,
+		newAlgorithm: newAlgorithm,
+		lease:        lease,
+		leases:       leases,
+		timeout:      timeout,
+		resources:    make(map[string]*Resource),
+	}
+	n.detector.watch = rt.healthChanged
+	n.changed = rt.membershipChanged
+	n.restore = rt.restore
+	return rt
+}
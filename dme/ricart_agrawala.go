@@ -0,0 +1,862 @@
+package dme
+
+import (
+	"context"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"mandatory-exercise-2/service"
+	"mandatory-exercise-2/utils"
+	"math"
+	"sync"
+	"time"
+)
+
+// RETRY_BACKOFF is the first delay before a request a peer could not be sent is sent again, when the failure detector is running.
+// The delay doubles up to the interval of the failure detector.
+const RETRY_BACKOFF = 100 * time.Millisecond
+
+// ricartAgrawala is the Ricart-Agrawala algorithm with the Roucairol-Carvalho optimization and shared (READ) requests.
+// A node multicasts a request to all peers and enters READ_HELD or WRITE_HELD when it holds the permission of all N-1 peers.
+// A reply is a permission which the node keeps until it replies to that peer's request,
+// so a node re-entering the critical section only asks the peers whose permission it has given away.
+// A reply to a READ request is only a permission to read, as the peer may be reading itself.
+// With k > 1 (k-mutual exclusion), a node enters after N-k replies, so up to k nodes hold the lock at the same time.
+// As a permission kept between entries does not keep k nodes out, every request is then sent to all peers.
+// With a lease, a node deferring requests renews its lease at the requesters, which otherwise treat it as released.
+// Replies and renewals carry the Lamport clock of their sender, and a node ticks its clock on entering,
+// so the Fence of an entry is greater than the fences of all entries before it.
+// With a failure detector, a node leaves DEAD peers out of its requests and its queue until they are ALIVE again.
+// A node giving up a request tells the peers it asked to forget it with Cancel.
+// A node joining the cluster holds no permissions, so a node in WANTED asks it for its permission,
+// and a node stops waiting for the permission of a node which has left.
+// With a write-ahead log, a node records its states, the requests it defers and its clock before acting on them,
+// and a restarted node recovers them before it takes part again.
+// With tracing, a request is traced across the cluster: the span enter lasts until the node holds the lock or gives up,
+// with a span for each Publish to a peer. A peer deferring the request traces the deferral until it sends the deferred reply.
+type ricartAgrawala struct {
+	*instance
+	state       int                   // The current state of the node.
+	timestamp   int32                 // timestamp is the Lamport time of the node's outstanding request.
+	mode        int32                 // mode is the lock mode of the node's outstanding request.
+	ctx         context.Context       // ctx is the context of the node's outstanding request.
+	mu          sync.Mutex            // mu guards all the fields below, state, timestamp, mode and the decision to enqueue a request.
+	queue       *utils.Queue          // queue is the node's FIFO queue of other nodes, with the timestamps of their requests.
+	permissions map[string]int32      // permissions is the lock mode of the permission the node holds of each peer, if any.
+	acquired    chan struct{}         // acquired is closed when the node enters the critical section.
+	failed      chan struct{}         // failed is closed when a peer could not be sent the outstanding request.
+	saved       int                   // saved is the total number of messages saved by the permissions held.
+	leases      map[string]time.Time  // leases is the expiry of the lease of each peer deferring the outstanding request.
+	latest      Fence                 // latest is the Fence of the node's latest entry into the critical section.
+	dropped     map[string]int32      // dropped is the timestamp of the latest request of each DEAD peer dropped from the queue.
+	cancelled   map[string]int32      // cancelled is the timestamp of the latest request each peer has given up.
+	since       time.Time             // since is the time the node entered WANTED, or the critical section, for the metrics.
+	span        trace.Span            // span is the span of the outstanding request, until the node holds the lock or gives up.
+	deferrals   map[string]trace.Span // deferrals is the span of the latest deferred request of each peer in the queue.
+}
+
+// lock blocks until the node is in WRITE_HELD, or until ctx is done.
+func (ra *ricartAgrawala) lock(ctx context.Context) error {
+	return ra.acquire(ctx, WRITE)
+}
+
+// rlock blocks until the node is in READ_HELD, or until ctx is done.
+func (ra *ricartAgrawala) rlock(ctx context.Context) error {
+	return ra.acquire(ctx, READ)
+}
+
+// acquire blocks until the node holds the lock in the given mode, or until ctx is done.
+// If ctx is done first, or a peer could not be reached, the request is abandoned.
+func (ra *ricartAgrawala) acquire(ctx context.Context, mode int32) error {
+	_, acquired, failed := ra.enter(ctx, mode)
+
+	select {
+	case <-acquired:
+		return nil
+	case <-failed:
+		if !ra.abandon() {
+			return ErrNotEnoughReplies
+		}
+	case <-ctx.Done():
+		if !ra.abandon() {
+			return ctx.Err()
+		}
+	}
+
+	return nil
+}
+
+// tryLock enters WRITE_HELD only if every peer it asks replies straight away.
+func (ra *ricartAgrawala) tryLock(ctx context.Context) bool {
+	return ra.tryAcquire(ctx, WRITE)
+}
+
+// tryRLock enters READ_HELD only if every peer it asks replies straight away.
+func (ra *ricartAgrawala) tryRLock(ctx context.Context) bool {
+	return ra.tryAcquire(ctx, READ)
+}
+
+// tryAcquire holds the lock in the given mode only if every peer it asks replies straight away.
+func (ra *ricartAgrawala) tryAcquire(ctx context.Context, mode int32) bool {
+	sent, acquired, _ := ra.enter(ctx, mode)
+	<-sent
+
+	select {
+	case <-acquired:
+		return true
+	default:
+	}
+
+	ra.logger.InfoPrintf("%v could not get the lock straight away.\n", ra.name)
+	return ra.abandon()
+}
+
+// unlock leaves WRITE_HELD and replies to all deferred peers.
+func (ra *ricartAgrawala) unlock() {
+	ra.release(WRITE_HELD)
+}
+
+// runlock leaves READ_HELD and replies to all deferred peers.
+func (ra *ricartAgrawala) runlock() {
+	ra.release(READ_HELD)
+}
+
+// release leaves the given held state and replies to all deferred peers.
+func (ra *ricartAgrawala) release(held int) {
+	ra.mu.Lock()
+	state := ra.state
+	ra.mu.Unlock()
+
+	if state != held {
+		ra.logger.WarningPrintf("%v tried to unlock without holding the lock.\n", ra.name)
+		return
+	}
+
+	ra.exit()
+	<-ra.local
+}
+
+// enter makes the node enter WANTED, timestamps its request in the given mode
+// and multicasts it to the peers whose permission it does not hold.
+// It returns the channel of multicast, and the channels acquired and failed of the request.
+func (ra *ricartAgrawala) enter(ctx context.Context, mode int32) (<-chan struct{}, <-chan struct{}, <-chan struct{}) {
+	<-ra.ready
+	ra.mu.Lock()
+	defer ra.mu.Unlock()
+
+	ra.lamport.Increment()
+	ra.timestamp = ra.lamport.Value()
+	ra.state = WANTED
+	ra.mode = mode
+	ctx, ra.span = ra.tracer.Start(ctx, "enter", trace.WithAttributes(
+		attribute.String("dme.node", ra.name),
+		attribute.String("dme.resource", ra.resource),
+		attribute.Int("dme.timestamp", int(ra.timestamp)),
+		attribute.Bool("dme.read", mode == READ),
+	))
+	ra.ctx = ctx
+	ra.since = time.Now()
+	ra.wal.entered(ra.resource, WANTED, ra.timestamp, mode, ra.lamport.Value())
+	ra.acquired = make(chan struct{})
+	ra.failed = make(chan struct{})
+	ra.logger.InfoPrintf("%v entered WANTED\n", ra.name)
+
+	if ra.k > 1 {
+		ra.permissions = make(map[string]int32)
+	}
+	ra.leases = make(map[string]time.Time)
+
+	receivers := make([]string, 0, ra.peers.len())
+	for _, name := range ra.peers.names() {
+		if !ra.permitted(name) && !ra.dead(name) {
+			receivers = append(receivers, name)
+		}
+	}
+
+	if saved := 2 * (ra.peers.len() - len(receivers)); saved > 0 {
+		ra.saved += saved
+		ra.logger.InfoPrintf("(%v) %v still holds %v/%v permissions, saving %v messages (%v in total).\n",
+			ra.timestamp, ra.name, ra.peers.len()-len(receivers), ra.peers.len(), saved, ra.saved)
+	}
+
+	sent := ra.multicast(ctx, receivers)
+	ra.measure()
+	ra.check()
+	return sent, ra.acquired, ra.failed
+}
+
+// permitted reports whether the node holds a permission of the peer name for the mode of its request. The mutex must be held.
+func (ra *ricartAgrawala) permitted(name string) bool {
+	mode, ok := ra.permissions[name]
+	return ok && (mode == WRITE || ra.mode == READ)
+}
+
+// dead reports whether the failure detector has declared the peer name DEAD.
+func (ra *ricartAgrawala) dead(name string) bool {
+	return ra.detector.healthOf(name) == DEAD
+}
+
+// holding reports whether the node is in READ_HELD or WRITE_HELD. The mutex must be held.
+func (ra *ricartAgrawala) holding() bool {
+	return ra.state == READ_HELD || ra.state == WRITE_HELD
+}
+
+// check makes the node enter READ_HELD or WRITE_HELD if it is in WANTED and holds the permission of N-k peers,
+// i.e. of all peers but k-1, not counting DEAD peers. The mutex must be held.
+func (ra *ricartAgrawala) check() {
+	if ra.state != WANTED {
+		return
+	}
+
+	missing := 0
+	for _, name := range ra.peers.names() {
+		if !ra.permitted(name) && !ra.dead(name) {
+			missing++
+		}
+	}
+
+	if missing > ra.k-1 {
+		return
+	}
+
+	ra.lamport.Increment() // Enter
+	ra.latest = newFence(ra.epoch, ra.lamport.Value())
+	if ra.mode == READ {
+		ra.state = READ_HELD
+		ra.logger.InfoPrintf("%v entered READ_HELD with the fence %v\n", ra.name, ra.latest)
+	} else {
+		ra.state = WRITE_HELD
+		ra.logger.InfoPrintf("%v entered WRITE_HELD with the fence %v\n", ra.name, ra.latest)
+	}
+	ra.wal.entered(ra.resource, ra.state, 0, 0, ra.lamport.Value())
+	ra.metrics.Wanted(ra.resource, time.Since(ra.since))
+	ra.since = time.Now()
+	ra.span.SetAttributes(attribute.String("dme.fence", ra.latest.String()))
+	ra.span.End()
+	ra.measure()
+	ra.logger.InfoPrintf("%v IS NOW IN THE CRITICAL SECTION!\n", ra.name)
+	close(ra.acquired)
+}
+
+// abandon withdraws the outstanding request, unless the node has entered the critical section in the meantime:
+// the node enters RELEASED, replies to all deferred peers and tells every peer it asked to forget the request.
+// Replies to the abandoned request arriving later are ignored. It reports whether the node is in the critical section.
+func (ra *ricartAgrawala) abandon() bool {
+	ra.mu.Lock()
+	if ra.holding() {
+		ra.mu.Unlock()
+		return true
+	}
+
+	asked := make([]string, 0, ra.peers.len())
+	for _, name := range ra.peers.names() {
+		if !ra.permitted(name) {
+			asked = append(asked, name)
+		}
+	}
+	r := &service.Request{Lamport: ra.timestamp, Name: ra.name, Resource: ra.resource}
+	ra.mu.Unlock()
+
+	ra.logger.InfoPrintf("(%v) %v abandoned its request.\n", r.Lamport, ra.name)
+	ra.exit()
+	for _, name := range asked {
+		go func(name string) {
+			if _, err := ra.peers.get(name).Cancel(context.Background(), r); err != nil {
+				ra.logger.ErrorPrintf("Could not cancel the request at %v. :: %v\n", name, err)
+			}
+		}(name)
+	}
+
+	<-ra.local
+	return false
+}
+
+// multicast sends the outstanding request to the given peers. The mutex must be held.
+// The returned channel is closed when every peer has answered the request.
+func (ra *ricartAgrawala) multicast(ctx context.Context, receivers []string) <-chan struct{} {
+	ra.logger.InfoPrintf("(%v, Send) %v is now multicasting to %v peers.\n", ra.timestamp, ra.name, len(receivers))
+
+	answers := sync.WaitGroup{}
+	sent := make(chan struct{})
+
+	timestamp, mode, failed := ra.timestamp, ra.mode, ra.failed
+	for _, receiver := range receivers {
+		ra.metrics.RequestSent(ra.resource)
+		answers.Add(1)
+		go func(receiverName string) {
+			defer answers.Done()
+			ra.request(ctx, receiverName, timestamp, mode, failed)
+		}(receiver)
+	}
+
+	go func() {
+		answers.Wait()
+		close(sent)
+	}()
+
+	return sent
+}
+
+// request sends the request with the given timestamp and mode to the peer receiverName.
+// failed is closed if the peer could not be reached, unless the failure detector is running:
+// the request is then sent again in the background until the peer answers it, or the peer is left out when it is DEAD.
+func (ra *ricartAgrawala) request(ctx context.Context, receiverName string, timestamp int32, mode int32, failed chan struct{}) {
+	if err := ra.publish(ctx, receiverName, timestamp, mode); err == nil {
+		return
+	}
+	if ra.detector.enabled() {
+		go ra.retry(ctx, receiverName, timestamp, mode)
+		return
+	}
+
+	ra.mu.Lock()
+	select {
+	case <-failed:
+	default:
+		close(failed)
+	}
+	ra.mu.Unlock()
+}
+
+// retry sends the request with the given timestamp and mode to the peer receiverName again, with a backoff from RETRY_BACKOFF
+// up to the interval of the failure detector, until the peer answers it. A peer which missed the request, after a transient error
+// or a restart within the Suspect window, is never declared DEAD, so nothing else would send it again.
+// It stops when the request is no longer outstanding at the peer, ctx is done or the node stops.
+func (ra *ricartAgrawala) retry(ctx context.Context, receiverName string, timestamp int32, mode int32) {
+	for backoff := RETRY_BACKOFF; ; backoff *= 2 {
+		if backoff > ra.detector.Interval {
+			backoff = ra.detector.Interval
+		}
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return
+		case <-ra.done:
+			return
+		}
+
+		if !ra.outstanding(receiverName, timestamp) {
+			return
+		}
+		if err := ra.publish(ctx, receiverName, timestamp, mode); err == nil {
+			return
+		}
+	}
+}
+
+// outstanding reports whether the node still waits for the permission of the peer name for its request with the given timestamp,
+// and the peer is neither DEAD nor gone.
+func (ra *ricartAgrawala) outstanding(name string, timestamp int32) bool {
+	ra.mu.Lock()
+	defer ra.mu.Unlock()
+
+	return ra.state == WANTED && ra.timestamp == timestamp && !ra.permitted(name) && ra.peers.has(name) && !ra.dead(name)
+}
+
+// publish sends the request with the given timestamp and mode to the peer receiverName once, and handles its answer.
+func (ra *ricartAgrawala) publish(ctx context.Context, receiverName string, timestamp int32, mode int32) error {
+	ra.logger.InfoPrintf("(%v, Send) %v is sending a request to %v.\n", timestamp, ra.name, receiverName)
+	ctx, span := ra.tracer.Start(ctx, "Publish", trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attribute.String("dme.peer", receiverName)))
+	defer span.End()
+
+	reply, err := ra.peers.get(receiverName).Publish(ctx, &service.Request{Lamport: timestamp, Name: ra.name, Resource: ra.resource, Mode: mode})
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		ra.logger.ErrorPrintf("Error sending request to %v. :: %v\n", receiverName, err)
+		return err
+	}
+
+	span.SetAttributes(attribute.Bool("dme.ack", reply.Ack))
+	if reply.Ack {
+		ra.replyReceived(receiverName, timestamp, reply.Lamport)
+	} else {
+		ra.deferralReceived(receiverName, timestamp, reply.Lamport, reply.Ttl)
+	}
+	return nil
+}
+
+// deferralReceived is called when the peer name with the Lamport clock clock defers the node's request with the given timestamp,
+// under a lease of ttl milliseconds, or no lease if ttl is 0.
+func (ra *ricartAgrawala) deferralReceived(name string, timestamp int32, clock int32, ttl int64) {
+	ra.mu.Lock()
+	defer ra.mu.Unlock()
+	ra.lamport.MaxAndIncrement(clock) // Receive
+
+	if ttl > 0 && ra.state == WANTED && timestamp == ra.timestamp && !ra.permitted(name) {
+		ra.extend(name, ttl)
+	}
+}
+
+// extend sets the lease of the peer name to expire in ttl milliseconds. The mutex must be held.
+func (ra *ricartAgrawala) extend(name string, ttl int64) {
+	duration := time.Duration(ttl) * time.Millisecond
+	ra.leases[name] = time.Now().Add(duration)
+	time.AfterFunc(duration, ra.expire)
+}
+
+// expire treats every peer whose lease has expired while the node is in WANTED as released:
+// the node takes the peer's reply as given and drops the peer from its queue.
+func (ra *ricartAgrawala) expire() {
+	ra.mu.Lock()
+	defer ra.mu.Unlock()
+
+	if ra.state != WANTED {
+		return
+	}
+
+	now := time.Now()
+	for name, expiry := range ra.leases {
+		if now.Before(expiry) {
+			continue
+		}
+
+		ra.logger.WarningPrintf("The lease of %v has expired. %v treats it as released.\n", name, ra.name)
+		delete(ra.leases, name)
+		for ra.queue.Remove(name) {
+		}
+		ra.undefer(name, "lease expired")
+		ra.wal.replied(ra.resource, name, math.MaxInt32, ra.lamport.Value())
+		ra.permissions[name] = ra.mode
+	}
+
+	ra.measure()
+	ra.check()
+}
+
+// heartbeat renews the node's lease at every peer in its queue, every renew interval of the lease, until the node stops.
+func (ra *ricartAgrawala) heartbeat() {
+	select {
+	case <-ra.ready:
+	case <-ra.done:
+		return
+	}
+
+	ticker := time.NewTicker(ra.lease.Renew)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-ra.done:
+			return
+		}
+
+		ra.mu.Lock()
+		deferred := make(map[string]bool)
+		for _, name := range ra.queue.Names() {
+			deferred[name] = true
+		}
+		ra.lamport.Increment() // Send renewals
+		r := &service.Request{Name: ra.name, Resource: ra.resource, Ttl: ra.lease.TTL.Milliseconds(), Clock: ra.lamport.Value()}
+		ra.mu.Unlock()
+
+		for name := range deferred {
+			go func(name string) {
+				ctx, cancel := context.WithTimeout(context.Background(), ra.lease.Renew)
+				defer cancel()
+				if _, err := ra.peers.get(name).Renew(ctx, r); err != nil {
+					ra.logger.WarningPrintf("%v could not renew its lease at %v. :: %v\n", ra.name, name, err)
+				}
+			}(name)
+		}
+	}
+}
+
+// replyReceived is called when a node receives a reply from the peer name with the Lamport clock clock
+// to its request with the given timestamp.
+// The node holds the peer's permission for the mode of the outstanding request if the reply is to that request.
+func (ra *ricartAgrawala) replyReceived(name string, timestamp int32, clock int32) {
+	ra.mu.Lock()
+	defer ra.mu.Unlock()
+	ra.lamport.MaxAndIncrement(clock) // Receive
+
+	if ra.state != WANTED || timestamp != ra.timestamp {
+		ra.logger.InfoPrintf("(%v) %v ignored an outdated reply from %v.\n", timestamp, ra.name, name)
+		return
+	}
+	ra.metrics.ReplyReceived(ra.resource)
+
+	ra.permissions[name] = ra.mode
+	delete(ra.leases, name)
+	ra.check()
+}
+
+// receive a service.Request from a node and either reply back to the node or enqueue it in the queue.
+// A request conflicts with the node's own request unless both are READ requests.
+// A conflicting request is deferred if the node is in the critical section, or if it is in WANTED
+// and its own request has a lower (timestamp, name) than the received one.
+// A request the peer has already given up is neither deferred nor replied to.
+// With k > 1, a node in the critical section may not have every peer's reply yet, and it still defers:
+// each holder keeps the peers it defers out, which bounds the holders to k.
+// Replying gives the node's permission away (or, for a READ request, limits it to reading),
+// so a node in WANTED which no longer holds enough permission asks the peer again.
+// A deferred request is traced as a child of the span of ctx until the reply is sent.
+// It returns whether the node replied, and its Lamport clock.
+func (ra *ricartAgrawala) receive(ctx context.Context, lamport int32, name string, mode int32) (bool, int32) {
+	defer ra.mu.Unlock()
+	ra.mu.Lock()
+	ra.logger.InfoPrintf("%v received request from %v.\n", ra.name, name)
+	ra.lamport.MaxAndIncrement(lamport) // Receive
+	ra.metrics.RequestReceived(ra.resource)
+	defer ra.measure()
+
+	if lamport <= ra.cancelled[name] {
+		ra.logger.InfoPrintf("(%v) %v ignored a cancelled request from %v.\n", lamport, ra.name, name)
+		return false, ra.lamport.Value()
+	}
+
+	conflicts := mode == WRITE || ra.mode == WRITE
+	if conflicts && (ra.holding() || (ra.state == WANTED && utils.Before(ra.timestamp, ra.name, lamport, name))) {
+		ra.queue.Enqueue(lamport, name)
+		ra.wal.deferred(ra.resource, name, lamport, ra.lamport.Value())
+		ra.metrics.Deferred(ra.resource)
+		ra.undefer(name, "superseded")
+		_, ra.deferrals[name] = ra.tracer.Start(ctx, "defer", trace.WithAttributes(
+			attribute.String("dme.node", ra.name),
+			attribute.String("dme.peer", name),
+			attribute.Int("dme.timestamp", int(lamport)),
+		))
+		ra.logger.InfoPrintf("%v is enqueued %v\n", ra.name, name)
+		return false, ra.lamport.Value()
+	}
+
+	ra.lamport.Increment() // Send reply back
+	ra.metrics.ReplySent(ra.resource)
+	ra.logger.InfoPrintf("(%v, Receive) %v is replying %v -> GO AHEAD!\n", ra.lamport.Value(), ra.name, name)
+
+	permitted := ra.permitted(name)
+	if _, ok := ra.permissions[name]; ok && mode == READ {
+		ra.permissions[name] = READ
+	} else {
+		delete(ra.permissions, name)
+	}
+
+	if ra.state == WANTED && permitted && !ra.permitted(name) {
+		go ra.request(ra.ctx, name, ra.timestamp, ra.mode, ra.failed)
+	}
+
+	return true, ra.lamport.Value()
+}
+
+// exit releases the CS and sends a reply to all deferred peers, giving away their permissions.
+// A reply carries the timestamp of the request it answers, which is the latest request of a peer
+// as an abandoned request may arrive after its successor.
+func (ra *ricartAgrawala) exit() {
+	ra.mu.Lock()
+	if ra.holding() {
+		ra.metrics.Held(ra.resource, time.Since(ra.since))
+	} else if ra.state == WANTED {
+		ra.metrics.Wanted(ra.resource, time.Since(ra.since))
+		ra.span.SetStatus(codes.Error, "abandoned")
+		ra.span.End()
+	}
+	ra.state = RELEASED
+	ra.leases = make(map[string]time.Time)
+	deferred := make(map[string]int32)
+	for !ra.queue.IsEmpty() {
+		lamport, name := ra.queue.Dequeue()
+		delete(ra.permissions, name)
+		if lamport > deferred[name] {
+			deferred[name] = lamport
+		}
+	}
+	ra.lamport.Increment() // Send replies
+	clock := ra.lamport.Value()
+	ra.wal.entered(ra.resource, RELEASED, 0, 0, clock)
+	for name, lamport := range deferred {
+		ra.wal.replied(ra.resource, name, lamport, clock)
+	}
+	ra.measure()
+	deferrals := ra.deferrals
+	ra.deferrals = make(map[string]trace.Span)
+	ra.mu.Unlock()
+
+	ra.logger.InfoPrintf("%v entered RELEASED\n", ra.name)
+
+	for name, lamport := range deferred {
+		ra.logger.InfoPrintf("%v dequeued %v\n", ra.name, name)
+		ra.reply(trace.ContextWithSpan(context.Background(), deferrals[name]), name, lamport, clock)
+	}
+	for _, span := range deferrals {
+		span.End()
+	}
+}
+
+// reply sends a deferred reply with the Lamport clock clock to the request of the peer name with the timestamp lamport.
+// The reply is traced as a child of the span of ctx, if any.
+func (ra *ricartAgrawala) reply(ctx context.Context, name string, lamport int32, clock int32) {
+	ctx, span := ra.tracer.Start(ctx, "ReplySender", trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attribute.String("dme.peer", name)))
+	defer span.End()
+
+	_, err := ra.peers.get(name).ReplySender(ctx, &service.Request{Name: ra.name, Lamport: lamport, Resource: ra.resource, Clock: clock})
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		ra.logger.ErrorPrintf("Could not dequeue %v. :: %v\n", name, err)
+		return
+	}
+	ra.metrics.ReplySent(ra.resource)
+}
+
+// undefer ends the span of the deferred request of the peer name, which has left the queue without a reply for the given reason.
+// The mutex must be held.
+func (ra *ricartAgrawala) undefer(name string, reason string) {
+	if span, ok := ra.deferrals[name]; ok {
+		span.AddEvent(reason)
+		span.End()
+		delete(ra.deferrals, name)
+	}
+}
+
+// queued reports whether a request of the peer name is in the queue. The mutex must be held.
+func (ra *ricartAgrawala) queued(name string) bool {
+	for _, n := range ra.queue.Names() {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// measure reports the length of the queue and the Lamport clock to the metrics. The mutex must be held.
+func (ra *ricartAgrawala) measure() {
+	ra.metrics.QueueLength(ra.resource, len(ra.queue.Names()))
+	ra.metrics.Clock(ra.resource, ra.lamport.Value())
+}
+
+// healthChanged leaves the peer name out when it is DEAD: its requests are dropped from the queue,
+// and the node no longer waits for its permission.
+// When the peer is ALIVE again, a dropped request is put back in the queue, or replied to if the node is not in the critical section,
+// and the outstanding request is sent to the peer again if the node does not hold its permission.
+func (ra *ricartAgrawala) healthChanged(name string, health int) {
+	ra.mu.Lock()
+	defer ra.mu.Unlock()
+	defer ra.measure()
+
+	switch health {
+	case DEAD:
+		for {
+			lamport, ok := ra.queue.Take(name)
+			if !ok {
+				break
+			}
+			if lamport > ra.dropped[name] {
+				ra.dropped[name] = lamport
+			}
+		}
+		ra.undefer(name, "peer dead")
+		ra.check()
+	case ALIVE:
+		if lamport, ok := ra.dropped[name]; ok {
+			delete(ra.dropped, name)
+			if ra.holding() {
+				ra.queue.Enqueue(lamport, name)
+			} else {
+				delete(ra.permissions, name)
+				ra.lamport.Increment() // Send reply
+				ra.wal.replied(ra.resource, name, lamport, ra.lamport.Value())
+				go ra.reply(context.Background(), name, lamport, ra.lamport.Value())
+			}
+		}
+
+		if ra.state == WANTED && !ra.permitted(name) {
+			go ra.request(ra.ctx, name, ra.timestamp, ra.mode, ra.failed)
+		}
+	}
+}
+
+// Publish receives requests from another node.
+func (ra *ricartAgrawala) Publish(ctx context.Context, r *service.Request) (*service.Reply, error) {
+	<-ra.ready
+	reply, clock := ra.receive(ctx, r.Lamport, r.Name, r.Mode)
+	if !reply {
+		return &service.Reply{Lamport: clock, Ack: false, Ttl: ra.lease.TTL.Milliseconds()}, nil
+	}
+	return &service.Reply{Lamport: clock, Ack: true}, nil
+}
+
+// ReplySender receives a deferred reply from a peer with the Lamport clock r.Clock to the request with the timestamp r.Lamport.
+func (ra *ricartAgrawala) ReplySender(_ context.Context, r *service.Request) (*service.Reply, error) {
+	<-ra.ready
+	ra.logger.InfoPrintf("(%v, Receive) %v received a reply from %v.\n", r.Lamport, ra.name, r.Name)
+	ra.replyReceived(r.Name, r.Lamport, r.Clock)
+	return &service.Reply{}, nil
+}
+
+// joined asks the peer name, which has just joined the cluster, for its permission if the node is in WANTED.
+func (ra *ricartAgrawala) joined(name string) {
+	ra.mu.Lock()
+	defer ra.mu.Unlock()
+
+	if ra.state == WANTED && !ra.permitted(name) {
+		go ra.request(ra.ctx, name, ra.timestamp, ra.mode, ra.failed)
+	}
+}
+
+// left forgets the peer name, which has left the cluster: its requests are dropped from the queue,
+// its permission is forgotten, and the node no longer waits for it.
+func (ra *ricartAgrawala) left(name string) {
+	ra.mu.Lock()
+	defer ra.mu.Unlock()
+
+	for ra.queue.Remove(name) {
+	}
+	ra.undefer(name, "peer left")
+	ra.wal.replied(ra.resource, name, math.MaxInt32, ra.lamport.Value())
+	delete(ra.permissions, name)
+	delete(ra.leases, name)
+	delete(ra.dropped, name)
+	delete(ra.cancelled, name)
+	ra.measure()
+	ra.check()
+}
+
+// leave replies to every request the node still owes a reply, before the node leaves the cluster.
+// It returns ErrBusy if the node is not in RELEASED.
+func (ra *ricartAgrawala) leave() error {
+	ra.mu.Lock()
+	if ra.state != RELEASED {
+		ra.mu.Unlock()
+		return ErrBusy
+	}
+
+	owed := ra.dropped
+	ra.dropped = make(map[string]int32)
+	ra.lamport.Increment() // Send replies
+	clock := ra.lamport.Value()
+	for name, lamport := range owed {
+		ra.wal.replied(ra.resource, name, lamport, clock)
+	}
+	ra.mu.Unlock()
+
+	for name, lamport := range owed {
+		ra.reply(context.Background(), name, lamport, clock)
+	}
+	return nil
+}
+
+// Cancel receives the withdrawal of the request with the timestamp r.Lamport from a peer.
+// The request is removed from the queue, or ignored if it arrives later.
+func (ra *ricartAgrawala) Cancel(_ context.Context, r *service.Request) (*service.Reply, error) {
+	<-ra.ready
+	ra.mu.Lock()
+	defer ra.mu.Unlock()
+	ra.logger.InfoPrintf("(%v, Receive) %v received a cancellation from %v.\n", r.Lamport, ra.name, r.Name)
+
+	ra.forget(r.Name, r.Lamport)
+	return &service.Reply{}, nil
+}
+
+// forget forgets the requests of the peer name up to the given timestamp, which it has given up:
+// they are removed from the queue, or ignored if they arrive later. The mutex must be held.
+func (ra *ricartAgrawala) forget(name string, timestamp int32) {
+	if timestamp > ra.cancelled[name] {
+		ra.cancelled[name] = timestamp
+	}
+	if ra.queue.RemoveUpTo(timestamp, name) {
+		ra.wal.replied(ra.resource, name, timestamp, ra.lamport.Value())
+		if !ra.queued(name) {
+			ra.undefer(name, "cancelled")
+		}
+		ra.measure()
+	}
+}
+
+// recover restores the state of the resource recorded in the node's write-ahead log before the node restarted,
+// and re-synchronises with the peers, before the node is ready.
+// The node lost the critical section and its request when it stopped, so it recovers in RELEASED:
+// it tells every peer to forget its requests up to the recorded one with Recover, and learns the peers' clocks.
+// Its clock, and so the fences of its entries, continue above every clock it recorded and every clock of its peers.
+// Finally it replies to every request it had deferred, so no peer waits forever for it.
+func (ra *ricartAgrawala) recover(s *walState) {
+	ra.mu.Lock()
+	ra.lamport.MaxAndIncrement(s.Clock) // Recover
+	r := &service.Request{Name: ra.name, Resource: ra.resource, Lamport: s.Timestamp, Clock: ra.lamport.Value()}
+	ra.mu.Unlock()
+
+	if s.State != RELEASED {
+		ra.logger.WarningPrintf("%v was not RELEASED on %q when it stopped. It gives up its request (%v).", ra.name, ra.resource, s.Timestamp)
+	}
+
+	for _, name := range ra.peers.names() {
+		reply, err := ra.peers.get(name).Recover(context.Background(), r)
+		if err != nil {
+			ra.logger.ErrorPrintf("Could not recover at %v. :: %v\n", name, err)
+			continue
+		}
+
+		ra.mu.Lock()
+		ra.lamport.MaxAndIncrement(reply.Lamport) // Receive
+		ra.mu.Unlock()
+	}
+
+	ra.mu.Lock()
+	ra.lamport.Increment() // Send replies
+	clock := ra.lamport.Value()
+	ra.wal.entered(ra.resource, RELEASED, 0, 0, clock)
+	for name, lamport := range s.Deferred {
+		ra.wal.replied(ra.resource, name, lamport, clock)
+	}
+	ra.mu.Unlock()
+
+	for name, lamport := range s.Deferred {
+		ra.logger.InfoPrintf("%v replies to %v, which it had deferred before it stopped.\n", ra.name, name)
+		ra.reply(context.Background(), name, lamport, clock)
+	}
+	ra.logger.WarningPrintf("%v RECOVERED %q WITH THE CLOCK %v.", ra.name, ra.resource, clock)
+}
+
+// Recover receives the recovery of a restarted peer: its requests up to r.Lamport are forgotten,
+// and the node answers with its Lamport clock.
+// Unlike other RPCs, Recover does not wait for the node to be ready, as the node may be recovering itself.
+func (ra *ricartAgrawala) Recover(_ context.Context, r *service.Request) (*service.Reply, error) {
+	ra.mu.Lock()
+	defer ra.mu.Unlock()
+	ra.logger.InfoPrintf("(%v, Receive) %v received the recovery of %v.\n", r.Lamport, ra.name, r.Name)
+
+	ra.lamport.MaxAndIncrement(r.Clock) // Receive
+	ra.forget(r.Name, r.Lamport)
+	return &service.Reply{Name: ra.name, Lamport: ra.lamport.Value()}, nil
+}
+
+// Renew receives a renewal of the lease of a peer deferring the node's request.
+func (ra *ricartAgrawala) Renew(_ context.Context, r *service.Request) (*service.Reply, error) {
+	<-ra.ready
+	ra.mu.Lock()
+	defer ra.mu.Unlock()
+	ra.logger.InfoPrintf("%v renewed its lease at %v.\n", r.Name, ra.name)
+	ra.lamport.MaxAndIncrement(r.Clock) // Receive
+
+	if ra.state == WANTED && !ra.permitted(r.Name) {
+		ra.extend(r.Name, r.Ttl)
+	}
+
+	return &service.Reply{}, nil
+}
+
+// fence returns the Fence of the node's latest entry into the critical section.
+func (ra *ricartAgrawala) fence() Fence {
+	ra.mu.Lock()
+	defer ra.mu.Unlock()
+
+	return ra.latest
+}
+
+// newRicartAgrawala creates the Ricart-Agrawala algorithm for the instance i.
+// With a lease, the node renews its lease at the peers it defers in the background.
+func newRicartAgrawala(i *instance) *ricartAgrawala {
+	ra := &ricartAgrawala{
+		instance:    i,
+		state:       RELEASED,
+		queue:       utils.NewQueue(),
+		permissions: make(map[string]int32),
+		leases:      make(map[string]time.Time),
+		dropped:     make(map[string]int32),
+		cancelled:   make(map[string]int32),
+		deferrals:   make(map[string]trace.Span),
+	}
+
+	if ra.lease.TTL > 0 {
+		go ra.heartbeat()
+	}
+
+	return ra
+}
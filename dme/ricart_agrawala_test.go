@@ -0,0 +1,137 @@
+package dme
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// deferred returns the names of the peers whose requests the node of m defers for the DefaultResource.
+func deferred(m *Mutex) []string {
+	ra := m.Resource(DefaultResource).algorithm.(*ricartAgrawala)
+	ra.mu.Lock()
+	defer ra.mu.Unlock()
+
+	return ra.queue.Names()
+}
+
+// TestLockTimeout checks that a Lock whose context ends first returns ErrTimeout,
+// and that its peers drop the withdrawn request instead of replying to it later, so other nodes can still lock.
+func TestLockTimeout(t *testing.T) {
+	ms := newCluster(t, 3, Config{})
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	if err := ms[0].Lock(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	expired, cancelExpired := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancelExpired()
+	err := ms[1].Lock(expired)
+	if !errors.Is(err, ErrTimeout) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Lock returned %v when its context expired, want ErrTimeout wrapping context.DeadlineExceeded", err)
+	}
+	if state := stateOf(ms[1]); state != RELEASED {
+		t.Errorf("d1 is in state %v after its Lock timed out, want RELEASED", state)
+	}
+
+	eventually(t, 5*time.Second, func() bool { return len(deferred(ms[0])) == 0 }, "d0 still defers the withdrawn request of d1")
+	ms[0].Unlock()
+
+	if err := ms[2].Lock(ctx); err != nil {
+		t.Fatalf("d2 could not lock after d1 withdrew its request: %v", err)
+	}
+	ms[2].Unlock()
+	contend(t, ms, 5)
+}
+
+// TestLockConfigTimeout checks that Config.Timeout bounds a Lock whose context never ends.
+func TestLockConfigTimeout(t *testing.T) {
+	ms := newCluster(t, 2, Config{Timeout: 200 * time.Millisecond})
+	if err := ms[0].Lock(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer ms[0].Unlock()
+
+	if err := ms[1].Lock(context.Background()); !errors.Is(err, ErrTimeout) {
+		t.Errorf("Lock returned %v after Config.Timeout, want ErrTimeout", err)
+	}
+}
+
+// leased reports whether the node of m waits for the peer name under a lease for the DefaultResource.
+func leased(m *Mutex, name string) bool {
+	ra := m.Resource(DefaultResource).algorithm.(*ricartAgrawala)
+	ra.mu.Lock()
+	defer ra.mu.Unlock()
+
+	_, ok := ra.leases[name]
+	return ok
+}
+
+// TestLeaseRenewed checks that a holder renewing its lease keeps the lock for longer than the TTL,
+// and that the waiting node enters only once it is released.
+func TestLeaseRenewed(t *testing.T) {
+	lease := Lease{TTL: 300 * time.Millisecond, Renew: 100 * time.Millisecond}
+	ms := newCluster(t, 2, Config{Lease: lease})
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	if err := ms[0].Lock(ctx); err != nil {
+		t.Fatal(err)
+	}
+	locked := make(chan error, 1)
+	go func() { locked <- ms[1].Lock(ctx) }()
+
+	select {
+	case err := <-locked:
+		t.Fatalf("d1 entered while d0 held the lock and renewed its lease: %v", err)
+	case <-time.After(3 * lease.TTL):
+	}
+
+	ms[0].Unlock()
+	if err := <-locked; err != nil {
+		t.Fatal(err)
+	}
+	ms[1].Unlock()
+}
+
+// TestLeaseExpired checks that a holder which stops renewing its lease loses the lock once it has expired:
+// the waiting node enters after the expiry, and not before.
+func TestLeaseExpired(t *testing.T) {
+	lease := Lease{TTL: 600 * time.Millisecond, Renew: 200 * time.Millisecond}
+	ms := newCluster(t, 2, Config{Lease: lease})
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	if err := ms[0].Lock(ctx); err != nil {
+		t.Fatal(err)
+	}
+	locked := make(chan error, 1)
+	go func() { locked <- ms[1].Lock(ctx) }()
+	eventually(t, 5*time.Second, func() bool { return leased(ms[1], "d0") }, "d1 did not wait for d0 under a lease")
+
+	// d0 hangs, and stops renewing its lease. Its last renewal was at most one Renew ago.
+	hung := ms[0].Resource(DefaultResource).algorithm.(*ricartAgrawala)
+	hung.mu.Lock()
+	since := time.Now()
+	var err error
+	select {
+	case err = <-locked:
+	case <-time.After(10 * lease.TTL):
+		err = fmt.Errorf("d1 did not enter %v after d0 stopped renewing its lease", 10*lease.TTL)
+	}
+	waited := time.Since(since)
+	hung.mu.Unlock()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	if waited < lease.TTL-lease.Renew {
+		t.Errorf("d1 entered %v after d0 stopped renewing, before its lease of %v could have expired", waited, lease.TTL)
+	}
+	ms[1].Unlock()
+	ms[0].Unlock()
+}
@@ -0,0 +1,258 @@
+package dme
+
+import (
+	"context"
+	"mandatory-exercise-2/service"
+	"sync"
+)
+
+// A token is the single privilege of the Suzuki-Kasami algorithm. Only the node holding it may enter HELD.
+type token struct {
+	last  map[string]int32 // last is the request number of the last granted request of each node (LN).
+	queue []string         // queue is the nodes waiting for the token.
+}
+
+// suzukiKasami is the Suzuki-Kasami token based algorithm.
+// A node broadcasts a numbered request for the token, unless it already holds it,
+// so entering the critical section costs either 0 or N messages.
+// A request cannot be cancelled, as the request numbers of the other nodes only grow. Instead, a node which has given up
+// its request passes the token on when it arrives, and a node requesting the token again waits for its outstanding request.
+type suzukiKasami struct {
+	*instance
+	mu       sync.Mutex         // mu guards all the fields below.
+	state    int                // The current state of the node.
+	requests map[string]int32   // requests is the highest request number received from each node, including this one (RN).
+	token    *token             // token is the token if the node holds it, otherwise nil.
+	acquired chan struct{}      // acquired is closed when the token arrives for the outstanding request.
+	pending  bool               // pending reports whether the node has broadcast a request which the token has not answered yet.
+	outboxes map[string]*outbox // outboxes are the outboxes to the other nodes.
+	once     sync.Once
+}
+
+// lock enters HELD straight away if the node holds the token. Otherwise it broadcasts a request,
+// unless a request given up before is still pending, and blocks until the token arrives, or until ctx is done.
+// If ctx is done first, the node will pass the token on as soon as it arrives.
+func (sk *suzukiKasami) lock(ctx context.Context) error {
+	sk.setup()
+	sk.mu.Lock()
+	sk.state = WANTED
+	sk.logger.InfoPrintf("%v entered WANTED\n", sk.name)
+
+	if sk.token != nil {
+		sk.held()
+		sk.mu.Unlock()
+		return nil
+	}
+
+	sk.acquired = make(chan struct{})
+	acquired := sk.acquired
+	if sk.pending {
+		sk.logger.InfoPrintf("(%v) %v is still waiting for the token for its earlier request.\n", sk.requests[sk.name], sk.name)
+	} else {
+		sk.pending = true
+		sk.requests[sk.name]++
+		r := &service.Request{Name: sk.name, Sequence: sk.requests[sk.name], Resource: sk.resource}
+		sk.logger.InfoPrintf("(%v, Send) %v is broadcasting a request for the token.\n", r.Sequence, sk.name)
+		for _, name := range sk.peers.names() {
+			sk.send(name, func(c service.ServiceClient) error {
+				_, err := c.Publish(context.Background(), r)
+				return err
+			})
+		}
+	}
+	sk.mu.Unlock()
+
+	select {
+	case <-acquired:
+		return nil
+	case <-ctx.Done():
+	}
+
+	sk.mu.Lock()
+	if sk.state == HELD {
+		sk.mu.Unlock()
+		return nil
+	}
+	sk.state = RELEASED
+	sk.logger.InfoPrintf("%v entered RELEASED\n", sk.name)
+	sk.mu.Unlock()
+	<-sk.local
+	return ctx.Err()
+}
+
+// tryLock enters HELD only if the node holds the token. No messages are sent.
+func (sk *suzukiKasami) tryLock(_ context.Context) bool {
+	sk.setup()
+	sk.mu.Lock()
+	defer sk.mu.Unlock()
+
+	if sk.token == nil {
+		sk.logger.InfoPrintf("%v could not get the lock straight away.\n", sk.name)
+		<-sk.local
+		return false
+	}
+
+	sk.held()
+	return true
+}
+
+// unlock leaves HELD, queues every node with an outstanding request and passes the token to the first of them.
+func (sk *suzukiKasami) unlock() {
+	sk.mu.Lock()
+	defer sk.mu.Unlock()
+
+	if sk.state != HELD {
+		sk.logger.WarningPrintf("%v tried to unlock without holding the lock.\n", sk.name)
+		return
+	}
+
+	sk.state = RELEASED
+	sk.logger.InfoPrintf("%v entered RELEASED\n", sk.name)
+	sk.release()
+	<-sk.local
+}
+
+// setup gives the token to the first of all members once the node has connected to all its peers.
+func (sk *suzukiKasami) setup() {
+	<-sk.ready
+	sk.once.Do(func() {
+		sk.mu.Lock()
+		defer sk.mu.Unlock()
+
+		sk.requests[sk.name] = 0
+		for _, name := range sk.peers.names() {
+			sk.requests[name] = 0
+		}
+
+		if sk.members()[0] == sk.name {
+			sk.token = &token{last: make(map[string]int32)}
+			for name := range sk.requests {
+				sk.token.last[name] = 0
+			}
+			sk.logger.InfoPrintf("%v holds the initial token.\n", sk.name)
+		}
+	})
+}
+
+// held makes the node enter HELD. The mutex must be held.
+func (sk *suzukiKasami) held() {
+	sk.state = HELD
+	sk.logger.InfoPrintf("%v entered HELD\n", sk.name)
+	sk.logger.InfoPrintf("%v IS NOW IN THE CRITICAL SECTION!\n", sk.name)
+}
+
+// release records the node's last request as granted, queues every node with an outstanding request
+// and passes the token to the first node in the queue. The mutex must be held.
+func (sk *suzukiKasami) release() {
+	sk.token.last[sk.name] = sk.requests[sk.name]
+
+	for _, name := range sk.members() {
+		if sk.outstanding(name) && !sk.queued(name) {
+			sk.token.queue = append(sk.token.queue, name)
+		}
+	}
+
+	if len(sk.token.queue) > 0 {
+		next := sk.token.queue[0]
+		sk.token.queue = sk.token.queue[1:]
+		sk.pass(next)
+	}
+}
+
+// outstanding reports whether the node name has a request which has not been granted yet. The mutex must be held.
+func (sk *suzukiKasami) outstanding(name string) bool {
+	return sk.requests[name] == sk.token.last[name]+1
+}
+
+// queued reports whether the node name is in the token's queue. The mutex must be held.
+func (sk *suzukiKasami) queued(name string) bool {
+	for _, queued := range sk.token.queue {
+		if queued == name {
+			return true
+		}
+	}
+	return false
+}
+
+// pass sends the token to the node to. The mutex must be held.
+func (sk *suzukiKasami) pass(to string) {
+	t := &service.Token{Name: sk.name, Queue: sk.token.queue, Resource: sk.resource}
+	for name, number := range sk.token.last {
+		t.Last = append(t.Last, &service.TokenEntry{Name: name, Number: number})
+	}
+	sk.token = nil
+
+	sk.logger.InfoPrintf("%v is passing the token to %v.\n", sk.name, to)
+	sk.send(to, func(c service.ServiceClient) error {
+		_, err := c.PassToken(context.Background(), t)
+		return err
+	})
+}
+
+// send posts a message to the node to.
+func (sk *suzukiKasami) send(to string, message func(c service.ServiceClient) error) {
+	o, ok := sk.outboxes[to]
+	if !ok {
+		o = newOutbox()
+		sk.outboxes[to] = o
+	}
+
+	o.post(func() {
+		if err := message(sk.peers.get(to)); err != nil {
+			sk.logger.ErrorPrintf("Error sending message to %v. :: %v\n", to, err)
+		}
+	})
+}
+
+// Publish receives a request for the token from another node.
+// An idle node holding the token passes it on straight away.
+func (sk *suzukiKasami) Publish(_ context.Context, r *service.Request) (*service.Reply, error) {
+	sk.setup()
+	sk.mu.Lock()
+	defer sk.mu.Unlock()
+	sk.logger.InfoPrintf("(%v, Receive) %v received a request for the token from %v.\n", r.Sequence, sk.name, r.Name)
+
+	if r.Sequence > sk.requests[r.Name] {
+		sk.requests[r.Name] = r.Sequence
+	}
+
+	if sk.token != nil && sk.state != HELD && sk.outstanding(r.Name) {
+		sk.pass(r.Name)
+	}
+
+	return &service.Reply{}, nil
+}
+
+// PassToken receives the token. The node enters HELD if it is waiting for the token,
+// otherwise it has given up its request and passes the token on.
+func (sk *suzukiKasami) PassToken(_ context.Context, t *service.Token) (*service.Reply, error) {
+	sk.setup()
+	sk.mu.Lock()
+	defer sk.mu.Unlock()
+	sk.logger.InfoPrintf("%v received the token from %v.\n", sk.name, t.Name)
+
+	sk.token = &token{last: make(map[string]int32), queue: t.Queue}
+	for _, entry := range t.Last {
+		sk.token.last[entry.Name] = entry.Number
+	}
+	sk.pending = false
+
+	if sk.state == WANTED {
+		sk.held()
+		close(sk.acquired)
+		return &service.Reply{}, nil
+	}
+
+	sk.release()
+	return &service.Reply{}, nil
+}
+
+// newSuzukiKasami creates the Suzuki-Kasami algorithm for the instance i.
+func newSuzukiKasami(i *instance) *suzukiKasami {
+	return &suzukiKasami{
+		instance: i,
+		state:    RELEASED,
+		requests: make(map[string]int32),
+		outboxes: make(map[string]*outbox),
+	}
+}
@@ -0,0 +1,93 @@
+package dme
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"os"
+)
+
+// A TLS describes the certificates with which the nodes of a cluster authenticate each other over mutual TLS.
+// Every node presents its certificate, whose common name must be its name, and accepts only peers presenting
+// a certificate signed by the CA whose common name is the name they claim in GetName and Join.
+// Either every node of a cluster uses TLS, or none does.
+type TLS struct {
+	Cert string // Cert is the path to the PEM certificate of the node. Empty disables TLS.
+	Key  string // Key is the path to the PEM private key of the certificate.
+	CA   string // CA is the path to the PEM certificate of the certificate authority of the cluster.
+}
+
+// enabled reports whether the node uses TLS.
+func (t TLS) enabled() bool {
+	return t.Cert != ""
+}
+
+// credentials loads the certificates into the transport credentials of both the server and the client side of the node.
+// Both sides require the other one to present a certificate signed by the CA.
+func (t TLS) credentials() (credentials.TransportCredentials, error) {
+	cert, err := tls.LoadX509KeyPair(t.Cert, t.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(t.CA)
+	if err != nil {
+		return nil, err
+	}
+	cas := x509.NewCertPool()
+	if !cas.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no certificate found in %v", t.CA)
+	}
+
+	return credentials.NewTLS(&tls.Config{
+		Certificates: []tls.Certificate{cert},
+		RootCAs:      cas,
+		ClientCAs:    cas,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		MinVersion:   tls.VersionTLS12,
+	}), nil
+}
+
+// certified returns a call option which records the peer answering a call, and a function which returns an error
+// unless that peer presented a certificate for the given name. Without TLS, every peer is certified.
+func (n *node) certified() (grpc.CallOption, func(name string) error) {
+	p := &peer.Peer{}
+	return grpc.Peer(p), func(name string) error { return n.certifies(p, name) }
+}
+
+// authenticate returns an error unless the caller of an RPC presented a certificate for the given name.
+// Without TLS, every caller is authenticated.
+func (n *node) authenticate(ctx context.Context, name string) error {
+	p, _ := peer.FromContext(ctx)
+	if err := n.certifies(p, name); err != nil {
+		n.logger.WarningPrintf("%v refuses a caller claiming to be %v. :: %v", n.name, name, err)
+		return status.Error(codes.Unauthenticated, err.Error())
+	}
+	return nil
+}
+
+// certifies returns an error unless the peer p presented a certificate whose common name is name.
+func (n *node) certifies(p *peer.Peer, name string) error {
+	if n.credentials == nil {
+		return nil
+	}
+	if p == nil {
+		return fmt.Errorf("no peer to authenticate as %v", name)
+	}
+
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(info.State.PeerCertificates) == 0 {
+		return fmt.Errorf("%v presented no certificate", p.Addr)
+	}
+
+	if cn := info.State.PeerCertificates[0].Subject.CommonName; cn != name {
+		return fmt.Errorf("%v presented a certificate for %q, not %q", p.Addr, cn, name)
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package dme
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"mandatory-exercise-2/service"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeCert signs a certificate for the common name cn valid for 127.0.0.1 with the CA ca and its key caKey,
+// or by itself if ca is nil, and writes it and its key to <file>.pem and <file>-key.pem in dir.
+func writeCert(t *testing.T, dir string, file string, cn string, ca *x509.Certificate, caKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	if ca == nil {
+		template.IsCA, template.BasicConstraintsValid = true, true
+		template.KeyUsage = x509.KeyUsageCertSign
+		ca, caKey = template, key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, ca, key.Public(), caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	if err := os.WriteFile(filepath.Join(dir, file+".pem"), certPEM, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, file+"-key.pem"), keyPEM, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, key
+}
+
+// TestTLSCommonName checks that a node refuses a caller of GetName and Join whose certificate is for another name
+// than the one it claims, and accepts a caller whose certificate is for its name.
+func TestTLSCommonName(t *testing.T) {
+	dir := t.TempDir()
+	ca, caKey := writeCert(t, dir, "ca", "test CA", nil, nil)
+	writeCert(t, dir, "d0", "d0", ca, caKey)
+	writeCert(t, dir, "d1", "d1", ca, caKey)
+	writeCert(t, dir, "impostor", "mallory", ca, caKey)
+	tlsOf := func(file string) TLS {
+		return TLS{Cert: filepath.Join(dir, file+".pem"), Key: filepath.Join(dir, file+"-key.pem"), CA: filepath.Join(dir, "ca.pem")}
+	}
+
+	ms := newCluster(t, 1, Config{TLS: tlsOf("d0")})
+	address := ms[0].node.ipAddress.String()
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	impostor := newTestMutex(t, Config{TLS: tlsOf("impostor")}, "d1", freePort(t)).node
+	nq := &service.NameRequest{Name: "d1", Algorithm: RICART_AGRAWALA, K: 1, Address: impostor.ipAddress.String()}
+	if _, err := impostor.dial(address).GetName(ctx, nq); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("GetName by d1 with a certificate for mallory returned %v, want Unauthenticated", err)
+	}
+	if _, err := impostor.dial(address).Join(ctx, nq); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("Join by d1 with a certificate for mallory returned %v, want Unauthenticated", err)
+	}
+	if ms[0].node.peers.has("d1") {
+		t.Error("d0 added d1 with a certificate for mallory to its peers")
+	}
+
+	m := newTestMutex(t, Config{TLS: tlsOf("d1")}, "d1", freePort(t))
+	nq.Address = m.node.ipAddress.String()
+	if _, err := m.node.dial(address).GetName(ctx, nq); err != nil {
+		t.Errorf("GetName by d1 with a certificate for d1 returned %v", err)
+	}
+	m.Join(address)
+	t.Cleanup(m.Stop)
+	if !ms[0].node.peers.has("d1") || !m.node.peers.has("d0") {
+		t.Errorf("d0 and d1 with certificates for their names did not join: %v, %v", ms[0].Members(), m.Members())
+	}
+}
@@ -0,0 +1,237 @@
+package dme
+
+import (
+	"bufio"
+	"encoding/json"
+	"mandatory-exercise-2/utils"
+	"os"
+	"sort"
+	"sync"
+)
+
+// WAL_COMPACT is the number of records after which a write-ahead log is compacted to a snapshot of its state.
+const WAL_COMPACT = 1000
+
+// Operations of the records of a write-ahead log.
+const (
+	WAL_STATE    = "state"    // WAL_STATE records that the node entered a state, with the timestamp and mode of its request.
+	WAL_DEFERRED = "deferred" // WAL_DEFERRED records that the node deferred the request of a peer.
+	WAL_REPLIED  = "replied"  // WAL_REPLIED records that the node replied to, or dropped, the requests of a peer up to a timestamp.
+)
+
+// A walRecord is a single record of a write-ahead log: a state transition or a queue mutation of a resource,
+// together with the Lamport clock of the resource.
+type walRecord struct {
+	Op        string `json:"op"`                  // Op is the operation of the record.
+	Resource  string `json:"resource"`            // Resource is the name of the resource.
+	Clock     int32  `json:"clock"`               // Clock is the Lamport clock of the resource.
+	State     int    `json:"state"`               // State is the state the node entered, for WAL_STATE. WANTED is 0, so it is never omitted.
+	Mode      int32  `json:"mode"`                // Mode is the lock mode of the node's request, for WAL_STATE.
+	Name      string `json:"name,omitempty"`      // Name is the name of the peer, for WAL_DEFERRED and WAL_REPLIED.
+	Timestamp int32  `json:"timestamp,omitempty"` // Timestamp is the timestamp of the request of the node or the peer.
+}
+
+// A walState is the state of a resource as recorded in a write-ahead log.
+type walState struct {
+	State     int              // State is the state of the node.
+	Timestamp int32            // Timestamp is the timestamp of the node's latest request.
+	Mode      int32            // Mode is the lock mode of the node's latest request.
+	Clock     int32            // Clock is the highest Lamport clock recorded.
+	Deferred  map[string]int32 // Deferred is the timestamp of the latest deferred request of each peer the node owes a reply.
+}
+
+// apply applies the record r to the state.
+func (s *walState) apply(r walRecord) {
+	if r.Clock > s.Clock {
+		s.Clock = r.Clock
+	}
+
+	switch r.Op {
+	case WAL_STATE:
+		s.State = r.State
+		if r.State == WANTED {
+			s.Timestamp, s.Mode = r.Timestamp, r.Mode
+		}
+	case WAL_DEFERRED:
+		if r.Timestamp > s.Deferred[r.Name] {
+			s.Deferred[r.Name] = r.Timestamp
+		}
+	case WAL_REPLIED:
+		if t, ok := s.Deferred[r.Name]; ok && t <= r.Timestamp {
+			delete(s.Deferred, r.Name)
+		}
+	}
+}
+
+// A recoverer is an algorithm which recovers its state from the node's write-ahead log after a restart.
+type recoverer interface {
+	recover(s *walState) // recover restores the recorded state s and re-synchronises with the peers, before the node is ready.
+}
+
+// A wal is the write-ahead log of a node, which records the state transitions, clocks and queue mutations of every resource
+// before the node acts on them, so a restarted node can recover them.
+// A wal without a file records nothing.
+type wal struct {
+	path    string               // path is the path to the log file.
+	logger  *utils.Logger        // logger is the log of the node.
+	mu      sync.Mutex           // mu guards all the fields below.
+	file    *os.File             // file is the log file, or nil if the node has no write-ahead log.
+	states  map[string]*walState // states is the recorded state of each resource.
+	records int                  // records is the number of records in the file.
+}
+
+// openWAL replays the write-ahead log at path, compacts it and opens it for appending.
+// A missing file is an empty log. An empty path disables the log.
+func openWAL(path string, logger *utils.Logger) *wal {
+	w := &wal{path: path, logger: logger, states: make(map[string]*walState)}
+	if path == "" {
+		return w
+	}
+
+	if f, err := os.Open(path); err == nil {
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			var r walRecord
+			if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+				logger.WarningPrintf("Ignoring a torn record at the end of the write-ahead log %v. :: %v", path, err)
+				break
+			}
+			w.state(r.Resource).apply(r)
+			w.records++
+		}
+		_ = f.Close()
+		logger.WarningPrintf("REPLAYED %v RECORDS OF THE WRITE-AHEAD LOG %v.", w.records, path)
+	} else if !os.IsNotExist(err) {
+		logger.ErrorFatalf("Could not open the write-ahead log %v. :: %v", path, err)
+	}
+
+	w.compact()
+	return w
+}
+
+// enabled reports whether the node has a write-ahead log.
+func (w *wal) enabled() bool {
+	return w.path != ""
+}
+
+// state returns the recorded state of the named resource, creating it if it has none. The mutex must be held.
+func (w *wal) state(resource string) *walState {
+	s, ok := w.states[resource]
+	if !ok {
+		s = &walState{State: RELEASED, Deferred: make(map[string]int32)}
+		w.states[resource] = s
+	}
+	return s
+}
+
+// recovered returns the recorded states of all resources, by name.
+func (w *wal) recovered() map[string]*walState {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	states := make(map[string]*walState, len(w.states))
+	for name, s := range w.states {
+		deferred := make(map[string]int32, len(s.Deferred))
+		for peer, t := range s.Deferred {
+			deferred[peer] = t
+		}
+		states[name] = &walState{State: s.State, Timestamp: s.Timestamp, Mode: s.Mode, Clock: s.Clock, Deferred: deferred}
+	}
+	return states
+}
+
+// append writes the record r to the log file and syncs it to disk, compacting the log once it has WAL_COMPACT records.
+func (w *wal) append(r walRecord) {
+	if !w.enabled() {
+		return
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.state(r.Resource).apply(r)
+	w.write(r)
+	if err := w.file.Sync(); err != nil {
+		w.logger.ErrorFatalf("Could not sync the write-ahead log %v. :: %v", w.path, err)
+	}
+
+	if w.records >= WAL_COMPACT {
+		w.compact()
+	}
+}
+
+// write writes the record r to the log file. The mutex must be held.
+func (w *wal) write(r walRecord) {
+	data, err := json.Marshal(r)
+	if err != nil {
+		w.logger.ErrorFatalf("Could not encode a record of the write-ahead log. :: %v", err)
+	}
+
+	if _, err := w.file.Write(append(data, '\n')); err != nil {
+		w.logger.ErrorFatalf("Could not write to the write-ahead log %v. :: %v", w.path, err)
+	}
+	w.records++
+}
+
+// compact replaces the log file with a snapshot of the recorded states, and opens it for appending. The mutex must be held.
+func (w *wal) compact() {
+	if w.file != nil {
+		_ = w.file.Close()
+	}
+
+	tmp := w.path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		w.logger.ErrorFatalf("Could not create the write-ahead log %v. :: %v", tmp, err)
+	}
+
+	w.file, w.records = f, 0
+	for _, resource := range w.resources() {
+		s := w.states[resource]
+		w.write(walRecord{Op: WAL_STATE, Resource: resource, Clock: s.Clock, State: WANTED, Mode: s.Mode, Timestamp: s.Timestamp})
+		if s.State != WANTED {
+			w.write(walRecord{Op: WAL_STATE, Resource: resource, Clock: s.Clock, State: s.State})
+		}
+		for name, t := range s.Deferred {
+			w.write(walRecord{Op: WAL_DEFERRED, Resource: resource, Clock: s.Clock, Name: name, Timestamp: t})
+		}
+	}
+
+	if err := f.Sync(); err != nil {
+		w.logger.ErrorFatalf("Could not sync the write-ahead log %v. :: %v", tmp, err)
+	}
+	_ = f.Close()
+	if err := os.Rename(tmp, w.path); err != nil {
+		w.logger.ErrorFatalf("Could not replace the write-ahead log %v. :: %v", w.path, err)
+	}
+
+	if w.file, err = os.OpenFile(w.path, os.O_APPEND|os.O_WRONLY, 0644); err != nil {
+		w.logger.ErrorFatalf("Could not open the write-ahead log %v. :: %v", w.path, err)
+	}
+}
+
+// resources returns the names of all recorded resources, sorted. The mutex must be held.
+func (w *wal) resources() []string {
+	names := make([]string, 0, len(w.states))
+	for name := range w.states {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// entered records that the node entered state for the resource, with the Lamport clock clock.
+// For WANTED, timestamp and mode are those of the node's request.
+func (w *wal) entered(resource string, state int, timestamp int32, mode int32, clock int32) {
+	w.append(walRecord{Op: WAL_STATE, Resource: resource, Clock: clock, State: state, Mode: mode, Timestamp: timestamp})
+}
+
+// deferred records that the node deferred the request of the peer name with the given timestamp.
+func (w *wal) deferred(resource string, name string, timestamp int32, clock int32) {
+	w.append(walRecord{Op: WAL_DEFERRED, Resource: resource, Clock: clock, Name: name, Timestamp: timestamp})
+}
+
+// replied records that the node no longer owes the peer name a reply to its requests up to the given timestamp.
+func (w *wal) replied(resource string, name string, timestamp int32, clock int32) {
+	w.append(walRecord{Op: WAL_REPLIED, Resource: resource, Clock: clock, Name: name, Timestamp: timestamp})
+}
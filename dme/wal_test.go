@@ -0,0 +1,125 @@
+package dme
+
+import (
+	"bufio"
+	"mandatory-exercise-2/utils"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// openTestWAL opens the write-ahead log at path with a logger in a temporary directory.
+func openTestWAL(t *testing.T, path string) *wal {
+	t.Helper()
+
+	logger := utils.NewLoggerIn(t.TempDir(), "wal")
+	logger.SetLevel(utils.ERROR)
+	return openWAL(path, logger)
+}
+
+// lines returns the lines of the file at path.
+func lines(t *testing.T, path string) []string {
+	t.Helper()
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	return lines
+}
+
+// TestWALReplay checks that a reopened write-ahead log recovers the state, request, clock and deferred requests of every resource.
+func TestWALReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "node.wal")
+	w := openTestWAL(t, path)
+	w.entered("users", WANTED, 5, READ, 6)
+	w.deferred("users", "b", 7, 8)
+	w.deferred("users", "c", 9, 10)
+	w.deferred("users", "c", 4, 11)
+	w.replied("users", "b", 7, 12)
+	w.entered("users", READ_HELD, 0, 0, 13)
+	w.entered("orders", WANTED, 2, WRITE, 3)
+
+	for _, line := range lines(t, path) {
+		if strings.Contains(line, `"op":"state"`) && !strings.Contains(line, `"state":`) {
+			t.Errorf("the state record %v has no state", line)
+		}
+	}
+
+	want := map[string]*walState{
+		"users":  {State: READ_HELD, Timestamp: 5, Mode: READ, Clock: 13, Deferred: map[string]int32{"c": 9}},
+		"orders": {State: WANTED, Timestamp: 2, Mode: WRITE, Clock: 3, Deferred: map[string]int32{}},
+	}
+	if got := openTestWAL(t, path).recovered(); !reflect.DeepEqual(got, want) {
+		t.Errorf("recovered %v, want %v", describe(got), describe(want))
+	}
+}
+
+// TestWALTornRecord checks that a torn record at the end of the log is ignored, and the records before it are replayed.
+func TestWALTornRecord(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "node.wal")
+	w := openTestWAL(t, path)
+	w.entered("users", WANTED, 5, WRITE, 6)
+	w.deferred("users", "b", 7, 8)
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteString(`{"op":"replied","resource":"us`); err != nil {
+		t.Fatal(err)
+	}
+	_ = f.Close()
+
+	want := map[string]*walState{"users": {State: WANTED, Timestamp: 5, Mode: WRITE, Clock: 8, Deferred: map[string]int32{"b": 7}}}
+	if got := openTestWAL(t, path).recovered(); !reflect.DeepEqual(got, want) {
+		t.Errorf("recovered %v, want %v", describe(got), describe(want))
+	}
+}
+
+// TestWALCompaction checks that a log is compacted to a snapshot once it has WAL_COMPACT records, and when it is opened,
+// and that the snapshot recovers the same state as the records.
+func TestWALCompaction(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "node.wal")
+	w := openTestWAL(t, path)
+
+	clock := int32(0)
+	for i := 0; i < WAL_COMPACT/4+10; i++ {
+		clock++
+		w.entered("users", WANTED, clock, WRITE, clock)
+		w.deferred("users", "b", clock, clock)
+		w.entered("users", WRITE_HELD, 0, 0, clock)
+		w.replied("users", "b", clock, clock)
+	}
+	w.deferred("users", "c", clock, clock)
+
+	if n := len(lines(t, path)); n >= WAL_COMPACT {
+		t.Errorf("the log has %v records, it was not compacted at %v", n, WAL_COMPACT)
+	}
+
+	want := map[string]*walState{"users": {State: WRITE_HELD, Timestamp: clock, Mode: WRITE, Clock: clock, Deferred: map[string]int32{"c": clock}}}
+	if got := openTestWAL(t, path).recovered(); !reflect.DeepEqual(got, want) {
+		t.Errorf("recovered %v, want %v", describe(got), describe(want))
+	}
+	if n := len(lines(t, path)); n != 3 {
+		t.Errorf("the log has %v records after it was reopened, want a snapshot of 3", n)
+	}
+}
+
+// describe returns the recovered states as a map of values, for messages.
+func describe(states map[string]*walState) map[string]walState {
+	values := make(map[string]walState, len(states))
+	for name, s := range states {
+		values[name] = *s
+	}
+	return values
+}
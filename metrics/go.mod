@@ -0,0 +1,3 @@
+module mandatory-exercise-2/metrics
+
+go 1.17
@@ -0,0 +1,246 @@
+// Package metrics exports the statistics of a node in the Prometheus text format, without depending on the Prometheus client library.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// BUCKETS are the upper bounds in seconds of the buckets of the histograms of the time spent in WANTED and in the critical section.
+var BUCKETS = []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+// Names of the metrics.
+const (
+	REQUESTS_SENT     = "dme_requests_sent_total"     // REQUESTS_SENT counts the requests sent to peers.
+	REQUESTS_RECEIVED = "dme_requests_received_total" // REQUESTS_RECEIVED counts the requests received from peers.
+	REPLIES_SENT      = "dme_replies_sent_total"      // REPLIES_SENT counts the replies sent to peers.
+	REPLIES_RECEIVED  = "dme_replies_received_total"  // REPLIES_RECEIVED counts the replies received from peers.
+	DEFERRALS         = "dme_deferrals_total"         // DEFERRALS counts the requests deferred.
+	WANTED_SECONDS    = "dme_wanted_seconds"          // WANTED_SECONDS is the histogram of the time spent in WANTED.
+	HELD_SECONDS      = "dme_held_seconds"            // HELD_SECONDS is the histogram of the time spent holding the lock.
+	QUEUE_LENGTH      = "dme_queue_length"            // QUEUE_LENGTH is the number of deferred requests.
+	LAMPORT_CLOCK     = "dme_lamport_clock"           // LAMPORT_CLOCK is the Lamport clock.
+	PEERS             = "dme_peers"                   // PEERS is the number of peers.
+)
+
+// Metrics of each type, in the order they are written.
+var (
+	COUNTERS   = []string{REQUESTS_SENT, REQUESTS_RECEIVED, REPLIES_SENT, REPLIES_RECEIVED, DEFERRALS}
+	HISTOGRAMS = []string{WANTED_SECONDS, HELD_SECONDS}
+	GAUGES     = []string{QUEUE_LENGTH, LAMPORT_CLOCK}
+)
+
+// help is the help text of every metric, by name.
+var help = map[string]string{
+	REQUESTS_SENT:     "Requests sent to peers.",
+	REQUESTS_RECEIVED: "Requests received from peers.",
+	REPLIES_SENT:      "Replies sent to peers, straight away or after deferring the request.",
+	REPLIES_RECEIVED:  "Replies received from peers.",
+	DEFERRALS:         "Requests of peers deferred.",
+	WANTED_SECONDS:    "Time spent in WANTED before holding the lock or giving up.",
+	HELD_SECONDS:      "Time spent holding the lock.",
+	QUEUE_LENGTH:      "Requests of peers waiting in the queue.",
+	LAMPORT_CLOCK:     "Lamport clock of the node.",
+	PEERS:             "Peers of the node.",
+}
+
+// A histogram is a Prometheus histogram with the BUCKETS.
+type histogram struct {
+	counts []uint64 // counts is the number of observations in each bucket, not cumulative.
+	sum    float64  // sum is the sum of all observations.
+	count  uint64   // count is the number of observations.
+}
+
+// observe adds the observation v to the histogram.
+func (h *histogram) observe(v float64) {
+	for i, bound := range BUCKETS {
+		if v <= bound {
+			h.counts[i]++
+			break
+		}
+	}
+	h.sum += v
+	h.count++
+}
+
+// A Prometheus collects the statistics of a node, and serves them in the Prometheus text format.
+// It implements dme.Metrics and http.Handler. Every metric is labelled with the name of the node,
+// and every metric but PEERS with the name of the resource.
+type Prometheus struct {
+	node       string                           // node is the name of the node.
+	mu         sync.Mutex                       // mu guards all the fields below.
+	counters   map[string]map[string]uint64     // counters is the value of every counter, by name and resource.
+	histograms map[string]map[string]*histogram // histograms is every histogram, by name and resource.
+	gauges     map[string]map[string]float64    // gauges is the value of every gauge, by name and resource.
+	peers      int                              // peers is the number of peers.
+	resources  map[string]bool                  // resources is the set of all resources with metrics.
+}
+
+// NewPrometheus creates the metrics of the node name.
+func NewPrometheus(name string) *Prometheus {
+	p := &Prometheus{
+		node:       name,
+		counters:   make(map[string]map[string]uint64),
+		histograms: make(map[string]map[string]*histogram),
+		gauges:     make(map[string]map[string]float64),
+		resources:  make(map[string]bool),
+	}
+	for _, name := range COUNTERS {
+		p.counters[name] = make(map[string]uint64)
+	}
+	for _, name := range HISTOGRAMS {
+		p.histograms[name] = make(map[string]*histogram)
+	}
+	for _, name := range GAUGES {
+		p.gauges[name] = make(map[string]float64)
+	}
+	return p
+}
+
+// inc increments the counter name of the resource.
+func (p *Prometheus) inc(name string, resource string) {
+	p.mu.Lock()
+	p.resources[resource] = true
+	p.counters[name][resource]++
+	p.mu.Unlock()
+}
+
+// observe adds the duration d to the histogram name of the resource.
+func (p *Prometheus) observe(name string, resource string, d time.Duration) {
+	p.mu.Lock()
+	p.resources[resource] = true
+	h, ok := p.histograms[name][resource]
+	if !ok {
+		h = &histogram{counts: make([]uint64, len(BUCKETS))}
+		p.histograms[name][resource] = h
+	}
+	h.observe(d.Seconds())
+	p.mu.Unlock()
+}
+
+// set sets the gauge name of the resource to v.
+func (p *Prometheus) set(name string, resource string, v float64) {
+	p.mu.Lock()
+	p.resources[resource] = true
+	p.gauges[name][resource] = v
+	p.mu.Unlock()
+}
+
+// RequestSent counts a request sent to a peer.
+func (p *Prometheus) RequestSent(resource string) { p.inc(REQUESTS_SENT, resource) }
+
+// RequestReceived counts a request received from a peer.
+func (p *Prometheus) RequestReceived(resource string) { p.inc(REQUESTS_RECEIVED, resource) }
+
+// ReplySent counts a reply sent to a peer.
+func (p *Prometheus) ReplySent(resource string) { p.inc(REPLIES_SENT, resource) }
+
+// ReplyReceived counts a reply received from a peer.
+func (p *Prometheus) ReplyReceived(resource string) { p.inc(REPLIES_RECEIVED, resource) }
+
+// Deferred counts a deferred request.
+func (p *Prometheus) Deferred(resource string) { p.inc(DEFERRALS, resource) }
+
+// Wanted observes the time d spent in WANTED.
+func (p *Prometheus) Wanted(resource string, d time.Duration) { p.observe(WANTED_SECONDS, resource, d) }
+
+// Held observes the time d spent holding the lock.
+func (p *Prometheus) Held(resource string, d time.Duration) { p.observe(HELD_SECONDS, resource, d) }
+
+// QueueLength sets the length of the queue.
+func (p *Prometheus) QueueLength(resource string, length int) {
+	p.set(QUEUE_LENGTH, resource, float64(length))
+}
+
+// Clock sets the Lamport clock.
+func (p *Prometheus) Clock(resource string, clock int32) {
+	p.set(LAMPORT_CLOCK, resource, float64(clock))
+}
+
+// Peers sets the number of peers.
+func (p *Prometheus) Peers(peers int) {
+	p.mu.Lock()
+	p.peers = peers
+	p.mu.Unlock()
+}
+
+// ServeHTTP serves all metrics in the Prometheus text format.
+func (p *Prometheus) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	p.Write(w)
+}
+
+// Write writes all metrics to w in the Prometheus text format, with the resources of each metric sorted by name.
+func (p *Prometheus) Write(w io.Writer) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	resources := make([]string, 0, len(p.resources))
+	for resource := range p.resources {
+		resources = append(resources, resource)
+	}
+	sort.Strings(resources)
+
+	for _, name := range COUNTERS {
+		header(w, name, "counter")
+		for _, resource := range resources {
+			fmt.Fprintf(w, "%v{%v} %v\n", name, p.labels(resource), p.counters[name][resource])
+		}
+	}
+
+	for _, name := range HISTOGRAMS {
+		header(w, name, "histogram")
+		for _, resource := range resources {
+			h, ok := p.histograms[name][resource]
+			if !ok {
+				h = &histogram{counts: make([]uint64, len(BUCKETS))}
+			}
+
+			labels := p.labels(resource)
+			cumulative := uint64(0)
+			for i, bound := range BUCKETS {
+				cumulative += h.counts[i]
+				fmt.Fprintf(w, "%v_bucket{%v,le=\"%v\"} %v\n", name, labels, number(bound), cumulative)
+			}
+			fmt.Fprintf(w, "%v_bucket{%v,le=\"+Inf\"} %v\n", name, labels, h.count)
+			fmt.Fprintf(w, "%v_sum{%v} %v\n", name, labels, number(h.sum))
+			fmt.Fprintf(w, "%v_count{%v} %v\n", name, labels, h.count)
+		}
+	}
+
+	for _, name := range GAUGES {
+		header(w, name, "gauge")
+		for _, resource := range resources {
+			fmt.Fprintf(w, "%v{%v} %v\n", name, p.labels(resource), number(p.gauges[name][resource]))
+		}
+	}
+
+	header(w, PEERS, "gauge")
+	fmt.Fprintf(w, "%v{node=%v} %v\n", PEERS, quote(p.node), p.peers)
+}
+
+// labels returns the labels of a metric of the resource.
+func (p *Prometheus) labels(resource string) string {
+	return "node=" + quote(p.node) + ",resource=" + quote(resource)
+}
+
+// header writes the HELP and TYPE lines of the metric name of the given type to w.
+func header(w io.Writer, name string, kind string) {
+	fmt.Fprintf(w, "# HELP %v %v\n# TYPE %v %v\n", name, help[name], name, kind)
+}
+
+// quote returns the label value v quoted and escaped for the Prometheus text format.
+func quote(v string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(v) + `"`
+}
+
+// number formats the sample value v for the Prometheus text format.
+func number(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,107 @@
+package metrics
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// update rewrites the golden files with the current output, instead of comparing against them.
+var update = flag.Bool("update", false, "update the golden files")
+
+// testPrometheus returns the metrics of a node with a counter, a gauge and a histogram observed,
+// named so the label values need escaping.
+func testPrometheus() *Prometheus {
+	p := NewPrometheus(`node "0"`)
+	resource := "a\\b\nc"
+
+	p.RequestSent(resource)
+	p.RequestSent(resource)
+	p.QueueLength(resource, 3)
+	p.Clock(resource, 7)
+	p.Wanted(resource, 3*time.Millisecond)
+	p.Wanted(resource, 40*time.Millisecond)
+	p.Wanted(resource, 2*time.Minute)
+	p.Held(resource, 500*time.Microsecond)
+	p.Peers(2)
+	return p
+}
+
+// TestWriteGolden checks the text format written by Write against testdata/prometheus.golden.
+func TestWriteGolden(t *testing.T) {
+	var out bytes.Buffer
+	testPrometheus().Write(&out)
+
+	golden := filepath.Join("testdata", "prometheus.golden")
+	if *update {
+		if err := os.WriteFile(golden, out.Bytes(), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != string(want) {
+		t.Errorf("Write wrote\n%v\nwant\n%v", out.String(), string(want))
+	}
+	if labels := `{node="node \"0\"",resource="a\\b\nc"}`; !strings.Contains(out.String(), labels) {
+		t.Errorf("Write did not escape the label values as %v", labels)
+	}
+}
+
+// TestWriteHistogram checks that the buckets of a histogram are cumulative and end with +Inf,
+// which counts every observation, followed by _sum and _count.
+func TestWriteHistogram(t *testing.T) {
+	var out bytes.Buffer
+	testPrometheus().Write(&out)
+
+	var buckets []uint64
+	var inf, count string
+	sum := false
+	scanner := bufio.NewScanner(&out)
+	for scanner.Scan() {
+		line := scanner.Text()
+		value := line[strings.LastIndex(line, " ")+1:]
+		switch {
+		case strings.HasPrefix(line, WANTED_SECONDS+`_bucket{`) && strings.Contains(line, `le="+Inf"`):
+			inf = value
+		case strings.HasPrefix(line, WANTED_SECONDS+`_bucket{`):
+			n, err := strconv.ParseUint(value, 10, 64)
+			if err != nil {
+				t.Fatalf("invalid bucket %v: %v", line, err)
+			}
+			buckets = append(buckets, n)
+		case strings.HasPrefix(line, WANTED_SECONDS+`_sum{`):
+			sum = true
+		case strings.HasPrefix(line, WANTED_SECONDS+`_count{`):
+			count = value
+		}
+	}
+
+	if len(buckets) != len(BUCKETS) {
+		t.Fatalf("%v has %v buckets besides +Inf, want %v", WANTED_SECONDS, len(buckets), len(BUCKETS))
+	}
+	for i := 1; i < len(buckets); i++ {
+		if buckets[i] < buckets[i-1] {
+			t.Errorf("the buckets of %v are not cumulative: %v", WANTED_SECONDS, buckets)
+			break
+		}
+	}
+	if last := buckets[len(buckets)-1]; last != 2 {
+		t.Errorf("the last bucket of %v counts %v observations, want 2", WANTED_SECONDS, last)
+	}
+	if inf != "3" || count != "3" {
+		t.Errorf("the +Inf bucket of %v is %q and its _count %q, want 3", WANTED_SECONDS, inf, count)
+	}
+	if !sum {
+		t.Errorf("%v has no _sum", WANTED_SECONDS)
+	}
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"mandatory-exercise-2/dme"
+	"mandatory-exercise-2/utils"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A cluster is a cluster file, which describes every node of a cluster.
+// All nodes share the same file, and each picks its own entry by name.
+type cluster struct {
+	Algorithm   string        `json:"algorithm" yaml:"algorithm"`     // Algorithm is the mutual exclusion algorithm of the cluster.
+	K           int           `json:"k" yaml:"k"`                     // K is the number of nodes which may hold the critical section at the same time.
+	Epoch       uint          `json:"epoch" yaml:"epoch"`             // Epoch is the epoch of the fencing tokens.
+	Coordinator string        `json:"coordinator" yaml:"coordinator"` // Coordinator is the name of the coordinator node of the centralized algorithm.
+	Resource    string        `json:"resource" yaml:"resource"`       // Resource is the name of the lock to enter the critical section of.
+	Hold        string        `json:"hold" yaml:"hold"`               // Hold is the time to hold the critical section at each entry, e.g. "500ms".
+	CA          string        `json:"ca" yaml:"ca"`                   // CA is the path to the certificate of the certificate authority of the cluster, for mutual TLS.
+	Secret      string        `json:"secret" yaml:"secret"`           // Secret is the secret of the cluster, with which every RPC is signed.
+	Log         logSettings   `json:"log" yaml:"log"`                 // Log describes the log files of the nodes.
+	Nodes       []clusterNode `json:"nodes" yaml:"nodes"`             // Nodes are the nodes of the cluster.
+}
+
+// A clusterNode is the entry of a single node in a cluster file.
+type clusterNode struct {
+	Name    string `json:"name" yaml:"name"`       // Name is the unique name of the node.
+	Address string `json:"address" yaml:"address"` // Address is the address of the node. Defaults to dme.DefaultAddress.
+	Port    int    `json:"port" yaml:"port"`       // Port is the server port of the node.
+	Delay   int    `json:"delay" yaml:"delay"`     // Delay is the delay in seconds before the node enters the critical section.
+	Parent  string `json:"parent" yaml:"parent"`   // Parent is the parent of the node in the raymond tree.
+	WAL     string `json:"wal" yaml:"wal"`         // WAL is the path to the write-ahead log of the node.
+	Cert    string `json:"cert" yaml:"cert"`       // Cert is the path to the certificate of the node, for mutual TLS.
+	Key     string `json:"key" yaml:"key"`         // Key is the path to the private key of the certificate of the node.
+	Metrics string `json:"metrics" yaml:"metrics"` // Metrics is the address to serve the Prometheus metrics of the node at.
+	Trace   string `json:"trace" yaml:"trace"`     // Trace is the file to export the traces of the node to.
+}
+
+// logSettings describes the log files of the nodes in a cluster file.
+type logSettings struct {
+	Dir    string `json:"dir" yaml:"dir"`       // Dir is the directory of the log files. Defaults to the log directory of utils.Logger.
+	Level  string `json:"level" yaml:"level"`   // Level is the level of the logs: info, warning or error. Defaults to info.
+	Delete bool   `json:"delete" yaml:"delete"` // Delete deletes the log file of a node when it exits.
+}
+
+// ipAddress returns the full ip address of the node.
+func (n clusterNode) ipAddress() string {
+	address := n.Address
+	if address == "" {
+		address = dme.DefaultAddress
+	}
+	return address + ":" + strconv.Itoa(n.Port)
+}
+
+// node returns the entry of the node name, and whether the cluster has one.
+func (c *cluster) node(name string) (clusterNode, bool) {
+	for _, n := range c.Nodes {
+		if n.Name == name {
+			return n, true
+		}
+	}
+	return clusterNode{}, false
+}
+
+// loadCluster reads the cluster file at path, which is YAML if its extension is .yaml or .yml, and JSON otherwise.
+func loadCluster(path string) (*cluster, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &cluster{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, c)
+	default:
+		err = json.Unmarshal(data, c)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	names := make(map[string]bool)
+	for _, n := range c.Nodes {
+		if n.Name == "" || n.Port == 0 {
+			return nil, fmt.Errorf("every node needs a name and a port")
+		}
+		if names[n.Name] {
+			return nil, fmt.Errorf("the name %v is used by more than one node", n.Name)
+		}
+		names[n.Name] = true
+	}
+
+	if c.Hold != "" {
+		if _, err := time.ParseDuration(c.Hold); err != nil {
+			return nil, fmt.Errorf("invalid hold %q: %w", c.Hold, err)
+		}
+	}
+	if c.Log.Level != "" {
+		if _, err := utils.ParseLevel(c.Log.Level); err != nil {
+			return nil, err
+		}
+	}
+
+	return c, nil
+}
+
+// flags returns the command line flags of the node name in the cluster, by flag name.
+// -ips lists every other node of the cluster. Values the file leaves empty are left out.
+func (c *cluster) flags(name string) (map[string]string, error) {
+	var self *clusterNode
+	var ips []string
+	for i, n := range c.Nodes {
+		if n.Name == name {
+			self = &c.Nodes[i]
+			continue
+		}
+		ips = append(ips, n.ipAddress())
+	}
+
+	if self == nil {
+		return nil, fmt.Errorf("%v is not a node of the cluster", name)
+	}
+
+	flags := map[string]string{
+		"sport": strconv.Itoa(self.Port),
+		"ips":   strings.Join(ips, ","),
+		"delay": strconv.Itoa(self.Delay),
+	}
+	set := func(flag string, value string) {
+		if value != "" {
+			flags[flag] = value
+		}
+	}
+	set("address", self.Address)
+	set("parent", self.Parent)
+	set("wal", self.WAL)
+	set("cert", self.Cert)
+	set("key", self.Key)
+	set("metrics", self.Metrics)
+	set("trace", self.Trace)
+	set("ca", c.CA)
+	set("secret", c.Secret)
+	set("algorithm", c.Algorithm)
+	set("coordinator", c.Coordinator)
+	set("resource", c.Resource)
+	set("hold", c.Hold)
+	set("loglevel", c.Log.Level)
+	if c.K != 0 {
+		set("k", strconv.Itoa(c.K))
+	}
+	if c.Epoch != 0 {
+		set("epoch", strconv.FormatUint(uint64(c.Epoch), 10))
+	}
+
+	return flags, nil
+}
+
+// explicitFlags returns the names of the flags of fs which have been set, i.e. given on the command line before the cluster file is applied.
+func explicitFlags(fs *flag.FlagSet) map[string]bool {
+	explicit := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
+	return explicit
+}
+
+// applyCluster sets the flags of fs of the node name from the cluster file at path.
+// Flags given on the command line take precedence over the file.
+func applyCluster(fs *flag.FlagSet, path string, name string) (*cluster, error) {
+	c, err := loadCluster(path)
+	if err != nil {
+		return nil, err
+	}
+
+	flags, err := c.flags(name)
+	if err != nil {
+		return nil, err
+	}
+
+	explicit := explicitFlags(fs)
+	for f, value := range flags {
+		if explicit[f] {
+			continue
+		}
+		if err := fs.Set(f, value); err != nil {
+			return nil, fmt.Errorf("invalid %v %q: %w", f, value, err)
+		}
+	}
+
+	return c, nil
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const clusterJSON = `{
+    "algorithm": "maekawa",
+    "hold": "500ms",
+    "k": 1,
+    "log": {"level": "warning"},
+    "nodes": [
+        {"name": "node0", "port": 8080, "delay": 5},
+        {"name": "node1", "address": "10.0.0.1", "port": 8081, "delay": 3},
+        {"name": "node2", "port": 8082}
+    ]
+}`
+
+const clusterYAML = `
+algorithm: maekawa
+hold: 500ms
+k: 1
+log:
+  level: warning
+nodes:
+  - {name: node0, port: 8080, delay: 5}
+  - {name: node1, address: 10.0.0.1, port: 8081, delay: 3}
+  - {name: node2, port: 8082}
+`
+
+// writeCluster writes the cluster file name with the given content to a temporary directory, and returns its path.
+func writeCluster(t *testing.T, name string, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// TestLoadCluster checks that a YAML and a JSON cluster file describe the same cluster.
+func TestLoadCluster(t *testing.T) {
+	fromJSON, err := loadCluster(writeCluster(t, "cluster.json", clusterJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromYAML, err := loadCluster(writeCluster(t, "cluster.yaml", clusterYAML))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(fromJSON, fromYAML) {
+		t.Errorf("the JSON cluster %+v differs from the YAML cluster %+v", fromJSON, fromYAML)
+	}
+	if len(fromJSON.Nodes) != 3 || fromJSON.Algorithm != "maekawa" || fromJSON.Log.Level != "warning" {
+		t.Errorf("unexpected cluster %+v", fromJSON)
+	}
+	if n, ok := fromJSON.node("node1"); !ok || n.ipAddress() != "10.0.0.1:8081" {
+		t.Errorf("node1 is %+v, %v", n, ok)
+	}
+	if n, _ := fromJSON.node("node2"); n.ipAddress() != "127.0.0.1:8082" {
+		t.Errorf("node2 without an address is at %v", n.ipAddress())
+	}
+}
+
+// TestLoadClusterInvalid checks that invalid cluster files are refused.
+func TestLoadClusterInvalid(t *testing.T) {
+	for name, content := range map[string]string{
+		"duplicate name": `{"nodes": [{"name": "node0", "port": 8080}, {"name": "node0", "port": 8081}]}`,
+		"no port":        `{"nodes": [{"name": "node0"}]}`,
+		"no name":        `{"nodes": [{"port": 8080}]}`,
+		"invalid hold":   `{"hold": "soon", "nodes": [{"name": "node0", "port": 8080}]}`,
+		"invalid level":  `{"log": {"level": "loud"}, "nodes": [{"name": "node0", "port": 8080}]}`,
+		"invalid JSON":   `{"nodes": [`,
+	} {
+		if _, err := loadCluster(writeCluster(t, "cluster.json", content)); err == nil {
+			t.Errorf("a cluster file with a %v was accepted", name)
+		}
+	}
+
+	if _, err := loadCluster(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("a missing cluster file was accepted")
+	}
+}
+
+// testFlags returns a flag set with the flags of a node the test cluster files set, parsed from args.
+func testFlags(t *testing.T, args ...string) (*flag.FlagSet, map[string]interface{}) {
+	t.Helper()
+
+	fs := flag.NewFlagSet("node", flag.ContinueOnError)
+	values := map[string]interface{}{
+		"address":   fs.String("address", "127.0.0.1", ""),
+		"sport":     fs.Int("sport", 8080, ""),
+		"ips":       fs.String("ips", "", ""),
+		"delay":     fs.Int("delay", 0, ""),
+		"algorithm": fs.String("algorithm", "ricart-agrawala", ""),
+		"k":         fs.Int("k", 1, ""),
+		"hold":      fs.Duration("hold", 5*time.Second, ""),
+		"loglevel":  fs.String("loglevel", "info", ""),
+	}
+	if err := fs.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	return fs, values
+}
+
+// TestApplyCluster checks that a node takes its flags from its entry of the cluster file,
+// and that flags given on the command line take precedence over the file.
+func TestApplyCluster(t *testing.T) {
+	path := writeCluster(t, "cluster.yaml", clusterYAML)
+	fs, values := testFlags(t, "-algorithm", "lamport", "-delay", "0")
+
+	if _, err := applyCluster(fs, path, "node1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := *values["algorithm"].(*string); got != "lamport" {
+		t.Errorf("-algorithm is %v, want lamport from the command line", got)
+	}
+	if got := *values["delay"].(*int); got != 0 {
+		t.Errorf("-delay is %v, want 0 from the command line", got)
+	}
+	if got := *values["hold"].(*time.Duration); got != 500*time.Millisecond {
+		t.Errorf("-hold is %v, want 500ms from the file", got)
+	}
+	if got := *values["sport"].(*int); got != 8081 {
+		t.Errorf("-sport is %v, want 8081 from the file", got)
+	}
+	if got := *values["address"].(*string); got != "10.0.0.1" {
+		t.Errorf("-address is %v, want 10.0.0.1 from the file", got)
+	}
+	if got := *values["loglevel"].(*string); got != "warning" {
+		t.Errorf("-loglevel is %v, want warning from the file", got)
+	}
+	if got := strings.Split(*values["ips"].(*string), ","); !reflect.DeepEqual(got, []string{"127.0.0.1:8080", "127.0.0.1:8082"}) {
+		t.Errorf("-ips is %v, want the other two nodes", got)
+	}
+
+	fs, _ = testFlags(t)
+	if _, err := applyCluster(fs, path, "node3"); err == nil {
+		t.Error("a node which is not in the cluster file was accepted")
+	}
+}
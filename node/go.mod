@@ -1,6 +1,6 @@
 module node
 
-go 1.17
+go 1.20
 
 replace mandatory-exercise-2/utils => ../utils
 
@@ -8,21 +8,35 @@ replace mandatory-exercise-2/server => ../server
 
 replace mandatory-exercise-2/client => ../client
 
+replace mandatory-exercise-2/dme => ../dme
+
 replace mandatory-exercise-2/service => ../service
 
+replace mandatory-exercise-2/metrics => ../metrics
+
 require (
-	mandatory-exercise-2/client v0.0.0-00010101000000-000000000000
-	mandatory-exercise-2/server v0.0.0-00010101000000-000000000000
-	mandatory-exercise-2/service v0.0.0-00010101000000-000000000000
+	go.opentelemetry.io/otel v1.21.0
+	go.opentelemetry.io/otel/exporters/stdout/stdouttrace v1.21.0
+	go.opentelemetry.io/otel/sdk v1.21.0
+	go.opentelemetry.io/otel/trace v1.21.0
+	gopkg.in/yaml.v3 v3.0.1
+	mandatory-exercise-2/dme v0.0.0-00010101000000-000000000000
+	mandatory-exercise-2/metrics v0.0.0-00010101000000-000000000000
 	mandatory-exercise-2/utils v0.0.0-00010101000000-000000000000
 )
 
 require (
+	github.com/go-logr/logr v1.3.0 // indirect
+	github.com/go-logr/stdr v1.2.2 // indirect
 	github.com/golang/protobuf v1.5.0 // indirect
+	go.opentelemetry.io/otel/metric v1.21.0 // indirect
 	golang.org/x/net v0.0.0-20200822124328-c89045814202 // indirect
-	golang.org/x/sys v0.0.0-20200323222414-85ca7c5b95cd // indirect
+	golang.org/x/sys v0.14.0 // indirect
 	golang.org/x/text v0.3.0 // indirect
 	google.golang.org/genproto v0.0.0-20200526211855-cb27e3aa2013 // indirect
 	google.golang.org/grpc v1.42.0 // indirect
 	google.golang.org/protobuf v1.27.1 // indirect
+	mandatory-exercise-2/client v0.0.0-00010101000000-000000000000 // indirect
+	mandatory-exercise-2/server v0.0.0-00010101000000-000000000000 // indirect
+	mandatory-exercise-2/service v0.0.0-00010101000000-000000000000 // indirect
 )
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"mandatory-exercise-2/dme"
+	"mandatory-exercise-2/metrics"
+	"mandatory-exercise-2/utils"
+	"net"
+	"net/http"
+)
+
+// serveMetrics serves the metrics of the node name at http://address/metrics in the Prometheus text format, until the program exits.
+func serveMetrics(address string, name string, logger *utils.Logger) dme.Metrics {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		logger.ErrorFatalf("Could not serve the metrics at %v. :: %v", address, err)
+	}
+
+	p := metrics.NewPrometheus(name)
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", p)
+	go func() {
+		if err := http.Serve(listener, mux); err != nil {
+			logger.ErrorPrintf("Stopped serving the metrics. :: %v\n", err)
+		}
+	}()
+
+	logger.WarningPrintf("SERVING THE METRICS OF %v AT http://%v/metrics", name, listener.Addr())
+	return p
+}
@@ -2,17 +2,16 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
-	"mandatory-exercise-2/client"
-	"mandatory-exercise-2/server"
-	"mandatory-exercise-2/service"
+	"go.opentelemetry.io/otel/trace"
+	"log"
+	"mandatory-exercise-2/dme"
 	"mandatory-exercise-2/utils"
-	"net"
+	"math/rand"
 	"os"
 	"os/signal"
-	"strconv"
 	"strings"
-	"sync"
 	"syscall"
 	"time"
 )
@@ -20,231 +19,199 @@ import (
 // deleteLogsAfterExit determines whether to delete the log file for this node or not at program termination.
 const deleteLogsAfterExit = false
 
-// defaultAddress is the default address of all nodes.
-const defaultAddress = "127.0.0.1"
-
-// Constants which represent the different states of a node.
-const (
-	WANTED   int = 0
-	HELD         = 1
-	RELEASED     = 2
-)
-
 var done = make(chan int)
-var wait sync.WaitGroup
-
-// A node is a single process running on an ip address.
-// It can communicate with other nodes.
-type node struct {
-	name       string                           // name is the id of the node.
-	ipAddress  *net.TCPAddr                     // ipAddress is the full ip address of the node.
-	state      int                              // The current state of the node.
-	server     *server.Server                   // server is the internal server.Server of the node.
-	logger     *utils.Logger                    // logger is a log which logs specified
-	lamport    *utils.Lamport                   // lamport is a logical clock.
-	queue      *utils.Queue                     // queue is the node's FIFO queue of other nodes.
-	repCounter *utils.Counter                   // repCounter counts the number of replies from other peers.
-	peers      map[string]service.ServiceClient // peers is a map of all the other nodes in the cluster, mapping a node name, to a service.ServiceClient.
-	delay      int			                    // delay time before entering state WANTED.
-	service.UnimplementedServiceServer
-}
 
 func main() {
+	var config = flag.String("config", "", "A cluster file (YAML or JSON) describing all nodes, of which the node with -name is started. Other flags override it.")
 	var name = flag.String("name", "node0", "The unique name of the node.")
-	var address = flag.String("address", defaultAddress, "The address of the node.")
+	var address = flag.String("address", dme.DefaultAddress, "The address of the node.")
 	var serverPort = flag.Int("sport", 8080, "The server port.")
 	var ipAddresses = flag.String("ips", "", "The ip addresses to the other nodes.")
+	var join = flag.String("join", "", "The ip address of any node of a running cluster to join, instead of -ips (ricart-agrawala only).")
 	var delay = flag.Int("delay", 0, "The delay start time.")
+	var algorithm = flag.String("algorithm", dme.RICART_AGRAWALA, "The mutual exclusion algorithm (ricart-agrawala, maekawa, suzuki-kasami, raymond, centralized or lamport).")
+	var k = flag.Int("k", 1, "The number of nodes which may hold the critical section at the same time (ricart-agrawala only).")
+	var epoch = flag.Uint("epoch", 0, "The epoch of the fencing tokens, which must be the same on every node. Raise it when restarting the cluster.")
+	var coordinator = flag.String("coordinator", "", "The name of the coordinator node in the centralized algorithm. Defaults to the first node in name order.")
+	var parent = flag.String("parent", "", "The name of the parent node in the raymond tree (the root names itself). Builds the tree automatically if empty.")
+	var resource = flag.String("resource", dme.DefaultResource, "The name of the lock to enter the critical section of.")
+	var read = flag.Bool("read", false, "Enter the critical section for reading, which other readers may do at the same time.")
+	var ttl = flag.Duration("ttl", 0, "The lease of the node on the critical section (ricart-agrawala only). 0 means it never expires.")
+	var renew = flag.Duration("renew", 0, "The interval between renewals of the lease. Defaults to a third of -ttl.")
+	var timeout = flag.Duration("timeout", 0, "The time to wait for the critical section before giving up the request. 0 means forever.")
+	var heartbeat = flag.Duration("heartbeat", 0, "The interval between heartbeats of the failure detector. 0 disables it.")
+	var suspect = flag.Duration("suspect", 0, "The silence after which a peer is suspected to have crashed. Defaults to 3 heartbeats.")
+	var dead = flag.Duration("dead", 0, "The silence after which a peer is declared dead. Defaults to 10 heartbeats.")
+	var gossip = flag.Duration("gossip", 0, "The interval between gossip probes, with which members are discovered and failed ones removed (ricart-agrawala only). 0 disables it.")
+	var walPath = flag.String("wal", "", "The path to the write-ahead log of the node, from which it recovers after a crash (ricart-agrawala only). Empty disables it.")
+	var cert = flag.String("cert", "", "The PEM certificate of the node for mutual TLS, whose common name must be -name. Empty disables TLS.")
+	var key = flag.String("key", "", "The PEM private key of the certificate of -cert.")
+	var ca = flag.String("ca", "", "The PEM certificate of the certificate authority which signed the certificates of all nodes.")
+	var secret = flag.String("secret", "", "The secret of the cluster, with which every RPC is signed and authenticated. Must be the same on every node. Empty disables it.")
+	var metricsAddress = flag.String("metrics", "", "The address to serve the Prometheus metrics of the node at, under /metrics, e.g. 127.0.0.1:9100. Empty disables it.")
+	var tracePath = flag.String("trace", "", "The file to export the traces of the node to, one JSON object per span. Empty disables tracing.")
+	var queue = flag.Duration("queue", 0, "The interval at which to log the holder and the wait queue of -resource (centralized only). 0 disables it.")
+	var count = flag.Int("count", 1, "The number of times to enter the critical section (0 = forever).")
+	var interval = flag.Duration("interval", 0, "The (mean) think time between two entries of the critical section.")
+	var think = flag.String("think", FIXED, "The think time distribution (fixed, uniform or exponential).")
+	var hold = flag.Duration("hold", 5*time.Second, "The time to hold the critical section at each entry.")
+	var logLevel = flag.String("loglevel", "info", "The level of the log (info, warning or error).")
 	flag.Parse()
+	explicit := explicitFlags(flag.CommandLine)
+
+	// Load the node's entry of the cluster file.
+	var c *cluster
+	logs, deleteLogs := "", deleteLogsAfterExit
+	if *config != "" {
+		var err error
+		if c, err = applyCluster(flag.CommandLine, *config, *name); err != nil {
+			log.Fatalf("Could not load the cluster file %v. :: %v", *config, err)
+		}
+		logs, deleteLogs = c.Log.Dir, deleteLogs || c.Log.Delete
+	}
 
 	// Setup close handler for CTRL + C
 	setupCloseHandler()
 
 	//Create and start the node.
-	n := newNode(*name, *address, *serverPort, *delay)
-	go n.start(*ipAddresses)
-
-	<-done
-	n.stop()
-	if deleteLogsAfterExit {
-		n.logger.DeleteLog()
-	}
-	os.Exit(0)
-}
-
-// registerPeer connects to and registers another node on this node at the specified port.
-func (n *node) registerPeer(ipAddress string) {
-	peer := client.NewClient(ipAddress, n.logger)
-	info, err := peer.GetName(context.Background(), &service.NameRequest{Name: n.name})
+	logger := utils.NewLoggerIn(logs, *name)
+	level, err := utils.ParseLevel(*logLevel)
 	if err != nil {
-		n.logger.ErrorFatalf("Could not fetch name of peer. :: %v", err)
+		logger.ErrorFatalf("Invalid log level. :: %v", err)
+	}
+	logger.SetLevel(level)
+	if !validThink(*think) {
+		logger.ErrorFatalf("Unknown think time distribution %v.", *think)
 	}
-	n.peers[info.Name] = peer
-}
-
-// start the node and connect to other peers (nodes).
-func (n *node) start(ipAddresses string) {
-	n.logger.WarningPrintln("STARTING NODE...")
-	n.server.Start(n.ipAddress.String(), n)
-	n.logger.WarningPrintln("NODE STARTED.")
-
-	peerAddresses := strings.Split(ipAddresses, ",")
-	for _, ipAddress := range peerAddresses {
-		address := ipAddress
-		if !strings.Contains(ipAddress, ":") {
-			address = defaultAddress + ":" + address
-		}
-
-		ip, err := net.ResolveTCPAddr("tcp", address)
-		if err != nil {
-			n.logger.ErrorPrintf("Invalid ip address %v. Skipping", address)
-			continue
-		}
 
-		if ip.String() == n.ipAddress.String() {
-			n.logger.WarningPrintf("Trying to connect to self (%v). Skipping!", address)
-			continue
-		}
+	rand.Seed(time.Now().UnixNano())
+	w := newWorkload(*count, *interval, *think, *hold, *read)
+	var metrics dme.Metrics
+	if *metricsAddress != "" {
+		metrics = serveMetrics(*metricsAddress, *name, logger)
+	}
 
-		n.registerPeer(address)
+	var tracing trace.TracerProvider
+	stopTracing := func() {}
+	if *tracePath != "" {
+		tracing, stopTracing = startTracing(*tracePath, *name, logger)
 	}
 
-	// Wait before entering WANTED.
-	time.Sleep(time.Duration(n.delay) * time.Second)
-	n.enter()
-}
+	m := dme.NewMutex(dme.Config{
+		Name:        *name,
+		Address:     *address,
+		Port:        *serverPort,
+		Algorithm:   *algorithm,
+		K:           *k,
+		Epoch:       uint32(*epoch),
+		Lease:       dme.Lease{TTL: *ttl, Renew: *renew},
+		Timeout:     *timeout,
+		Detector:    dme.FailureDetector{Interval: *heartbeat, Suspect: *suspect, Dead: *dead},
+		Gossip:      dme.Gossip{Interval: *gossip},
+		WAL:         *walPath,
+		TLS:         dme.TLS{Cert: *cert, Key: *key, CA: *ca},
+		Secret:      *secret,
+		Metrics:     metrics,
+		Tracing:     tracing,
+		Parent:      *parent,
+		Coordinator: *coordinator,
+		Logger:      logger,
+	})
+	var rl *reloader
+	if c != nil {
+		rl = newReloader(*config, c, m, logger, w, explicit)
+	}
+	go run(m, *resource, logger, strings.Split(*ipAddresses, ","), *join, *delay, w, rl)
+	if *queue > 0 {
+		go logQueue(m.Resource(*resource), *queue, logger)
+	}
 
-// stop shutdowns the node.
-func (n *node) stop() {
-	n.logger.WarningPrintln("STOPPING NODE...")
-	n.server.Stop()
-	n.logger.WarningPrintln("NODE STOPPED.")
+	<-done
+	if err := m.Leave(); errors.Is(err, dme.ErrMembership) {
+		m.Stop()
+	} else if err != nil {
+		logger.WarningPrintf("%v could not leave the cluster. :: %v", m.Name(), err)
+		m.Stop()
+	}
+	stopTracing()
+	if deleteLogs {
+		logger.DeleteLog()
+	}
+	os.Exit(0)
 }
 
-// enter makes the node enter WANTED.
-// It multicasts a request to all peers and waits for replies.
-// N-1 replies must be received before entering HELD.
-func (n *node) enter() {
-	n.state = WANTED
-	n.logger.InfoPrintf("%v entered WANTED\n", n.name)
-
-	// Multicast to all peers
-	replies := n.multicast()
-
-	if replies == len(n.peers) {
-		n.state = HELD
-		n.logger.InfoPrintf("%v entered HELD\n", n.name)
-		n.logger.InfoPrintf("%v IS NOW IN THE CRITICAL SECTION!\n", n.name)
-		time.Sleep(5 * time.Second)
-		n.exit()
+// run starts the node, or joins the cluster of the node at the join address if it is not empty,
+// starts the reloader of the cluster file if there is one,
+// waits delay seconds and then enters the critical section of resource as described by the workload.
+func run(m *dme.Mutex, resource string, logger *utils.Logger, ipAddresses []string, join string, delay int, w *workload, rl *reloader) {
+	if join != "" {
+		m.Join(join)
 	} else {
-		n.logger.WarningPrintf("%v did not get enough repCounter: %v/%v", n.name, replies, len(n.peers))
+		m.Start(ipAddresses)
 	}
-}
-
-// multicast sends a request to all peers of a node and counts and returns the number of peers responding.
-func (n *node) multicast() int {
-	n.lamport.Increment()
-	n.logger.InfoPrintf("(%v, Send) %v is now multicasting to peers.\n", n.lamport.Value(), n.name)
-
-	wait = sync.WaitGroup{}
-	doneSending := make(chan int)
-
-	// Reset counter at each multicast call.
-	n.repCounter.Reset()
-
-	for clientName, serviceClient := range n.peers {
-		wait.Add(1)
-
-		go func(receiverName string, client service.ServiceClient) {
-			n.logger.InfoPrintf("(%v, Send) %v is sending a request to %v.\n", n.lamport.Value(), n.name, receiverName)
-
-			reply, err := client.Publish(context.Background(), &service.Request{Lamport: n.lamport.Value(), Name: n.name})
-			if err != nil {
-				n.logger.ErrorPrintf("Error sending request to %v. :: %v\n", receiverName, err)
-			}
-
-			if reply.Ack {
-				n.replyReceived()
-			}
-		}(clientName, serviceClient)
+	if rl != nil {
+		go rl.watch()
 	}
+	r := m.Resource(resource)
 
-	go func() {
-		wait.Wait()
-		close(doneSending)
-	}()
-
-	<-doneSending
-	n.logger.InfoPrintf("(%v) %v done multicasting. Replies: %v/%v\n", n.lamport.Value(), n.name, n.repCounter.Value(), len(n.peers))
+	// Wait before entering WANTED.
+	time.Sleep(time.Duration(delay) * time.Second)
 
-	return n.repCounter.Value()
-}
+	for round := 1; ; round++ {
+		lock, unlock := r.Lock, r.Unlock
+		if w.read {
+			lock, unlock = r.RLock, r.RUnlock
+		}
 
-// replyReceived is called when a node receives a reply.
-// It decrements wait and increments repCounter.
-func (n *node) replyReceived() {
-	n.repCounter.Increment()
-	wait.Done()
-}
+		w.enter()
+		if err := lock(context.Background()); err != nil {
+			logger.ErrorPrintf("%v could not enter the critical section in round %v. :: %v\n", m.Name(), round, err)
+		} else {
+			hold := w.holdTime()
+			if fence, err := r.Fence(); err == nil {
+				logger.InfoPrintf("%v holds the critical section for %v with the fence %v (round %v).\n", m.Name(), hold, fence, round)
+			} else {
+				logger.InfoPrintf("%v holds the critical section for %v (round %v).\n", m.Name(), hold, round)
+			}
+			time.Sleep(hold)
+			unlock()
+		}
+		w.exit()
 
-// receive a service.Request from a node and either reply back to the node or enqueue it in the queue.
-func (n *node) receive(lamport int32, name string) bool {
-	n.logger.InfoPrintf("%v received request from %v.\n", n.name, name)
+		if !w.more(round) {
+			break
+		}
 
-	if n.state == HELD || (n.state == WANTED && n.lamport.CompareLamportAndProcess(n.name, lamport, name)) {
-		n.queue.Enqueue(lamport, name)
-		n.logger.InfoPrintf("%v is enqueued %v\n", n.name, name)
-		return false
+		// Think before entering WANTED again.
+		time.Sleep(w.thinkTime())
 	}
 
-	n.logger.InfoPrintf("(%v, Receive) %v is replying %v -> GO AHEAD!\n", n.lamport.Value(), n.name, name)
-	n.lamport.MaxAndIncrement(lamport) // Receive
-	n.lamport.Increment()              // Send reply back
-	return true
+	logger.WarningPrintf("%v is done entering the critical section.", m.Name())
 }
 
-// exit releases the CS and sends a reply to all peers.
-func (n *node) exit() {
-	n.state = RELEASED
-	n.logger.InfoPrintf("%v entered RELEASED\n", n.name)
+// logQueue logs the holder and the wait queue of the resource r every interval, once the node has started.
+func logQueue(r *dme.Resource, interval time.Duration, logger *utils.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		ctx, cancel := context.WithTimeout(context.Background(), interval)
+		holder, queue, err := r.Queue(ctx)
+		cancel()
 
-	for !n.queue.IsEmpty() {
-		_, name := n.queue.Dequeue()
-		n.logger.InfoPrintf("%v dequeued %v\n", n.name, name)
+		if errors.Is(err, dme.ErrNoQueue) {
+			logger.WarningPrintf("The algorithm of the cluster has no wait queue to log. :: %v", err)
+			return
+		} else if err != nil {
+			continue
+		}
 
-		_, err := n.peers[name].ReplySender(context.Background(), &service.Request{Name: n.name, Lamport: n.lamport.Value()})
-		if err != nil {
-			n.logger.ErrorPrintf("Could not dequeue %v. :: %v\n", name, err)
+		if holder == "" {
+			holder = "nobody"
 		}
+		logger.WarningPrintf("%q is held by %v, and %v node(s) wait for it: %v", r.Name(), holder, len(queue), queue)
 	}
 }
 
-// Publish receives requests from another node.
-func (n *node) Publish(_ context.Context, r *service.Request) (*service.Reply, error) {
-	reply := n.receive(r.Lamport, r.Name)
-	return &service.Reply{Ack: reply}, nil
-}
-
-// ReplySender sends a reply from a node which is received at the peer.
-func (n *node) ReplySender(_ context.Context, r *service.Request) (*service.Reply, error) {
-	n.logger.InfoPrintf("(%v, Send) %v is replying %v.\n", n.lamport.Value(), r.Name, n.name)
-	n.replyReceived()
-	return &service.Reply{}, nil
-}
-
-// GetName returns an info struct to the caller.
-func (n *node) GetName(_ context.Context, nq *service.NameRequest) (*service.NameReply, error) {
-	n.logger.InfoPrintf("%v is requesting the name of %v.\n", nq.Name, n.name)
-	n.logger.InfoPrintf("Sending back %v.\n", n.name)
-	return &service.NameReply{Name: n.name}, nil
-}
-
-// createIpAddress converts an address string and a port (integer) to a string.
-func createIpAddress(address string, port int) string {
-	return address + ":" + strconv.Itoa(port)
-}
-
 // setupCloseHandler sets a close handler for this program if it is interrupted.
 func setupCloseHandler() {
 	c := make(chan os.Signal, 2)
@@ -254,27 +221,3 @@ func setupCloseHandler() {
 		close(done)
 	}()
 }
-
-// newNode creates a new node with the specified unique name and ip address.
-func newNode(name string, address string, serverPort int, delay int) *node {
-	logger := utils.NewLogger(name)
-	logger.WarningPrintf("CREATING NODE WITH ID '%v' AND IP ADDRESS '%v:%v'", name, address, serverPort)
-
-	ipAddress, err := net.ResolveTCPAddr("tcp", createIpAddress(address, serverPort))
-	if err != nil {
-		logger.ErrorFatalf("Error resolving tcp address %v:%v. :: %v", address, serverPort, err)
-	}
-
-	return &node{
-		name:       name,
-		ipAddress:  ipAddress,
-		state:      RELEASED,
-		server:     server.NewServer(logger),
-		lamport:    utils.NewLamport(),
-		queue:      utils.NewQueue(),
-		repCounter: utils.NewCounter(),
-		peers:      make(map[string]service.ServiceClient),
-		delay:      delay,
-		logger:     logger,
-	}
-}
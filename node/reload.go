@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"mandatory-exercise-2/dme"
+	"mandatory-exercise-2/utils"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// reloadInterval is the interval at which a reloader checks whether the cluster file has changed.
+const reloadInterval = time.Second
+
+// addPeerTimeout bounds the time to add a peer added to the cluster file.
+const addPeerTimeout = 10 * time.Second
+
+// A reloader applies the changes of the cluster file to the running node whenever the file changes, or the node receives SIGHUP.
+// The log level and the hold time change at once, unless they are given on the command line, which takes precedence like at startup.
+// Peers are added and removed only while the node is RELEASED, so changes made while it is WANTED or HELD are delayed until it is RELEASED again.
+// Other changes, such as the algorithm, need a restart.
+type reloader struct {
+	path     string          // path is the path to the cluster file.
+	m        *dme.Mutex      // m is the Mutex of the node.
+	logger   *utils.Logger   // logger is the log of the node.
+	w        *workload       // w is the workload of the node.
+	cluster  *cluster        // cluster is the cluster file as last applied.
+	modified time.Time       // modified is the modification time of the cluster file as last applied.
+	explicit map[string]bool // explicit are the flags given on the command line, which the cluster file does not change.
+}
+
+// newReloader creates a reloader of the cluster file at path, which has been applied as c
+// to a node whose explicit flags were given on the command line.
+func newReloader(path string, c *cluster, m *dme.Mutex, logger *utils.Logger, w *workload, explicit map[string]bool) *reloader {
+	rl := &reloader{path: path, m: m, logger: logger, w: w, cluster: c, explicit: explicit}
+	if info, err := os.Stat(path); err == nil {
+		rl.modified = info.ModTime()
+	}
+	return rl
+}
+
+// watch reloads the cluster file whenever it changes or the node receives SIGHUP, until the program exits.
+func (rl *reloader) watch() {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	ticker := time.NewTicker(reloadInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-hup:
+			rl.logger.WarningPrintf("%v received SIGHUP. Reloading %v.", rl.m.Name(), rl.path)
+		case <-ticker.C:
+			info, err := os.Stat(rl.path)
+			if err != nil || info.ModTime().Equal(rl.modified) {
+				continue
+			}
+			rl.logger.WarningPrintf("%v has changed. Reloading it.", rl.path)
+		}
+
+		rl.reload()
+	}
+}
+
+// reload loads the cluster file, logs what changed and applies it.
+// A file which cannot be loaded, or which no longer has an entry for the node, is ignored.
+func (rl *reloader) reload() {
+	if info, err := os.Stat(rl.path); err == nil {
+		rl.modified = info.ModTime()
+	}
+
+	c, err := loadCluster(rl.path)
+	if err != nil {
+		rl.logger.ErrorPrintf("Could not reload the cluster file %v. Keeping the old one. :: %v\n", rl.path, err)
+		return
+	}
+	if _, ok := c.node(rl.m.Name()); !ok {
+		rl.logger.ErrorPrintf("%v is no longer a node of the cluster file %v. Keeping the old one.\n", rl.m.Name(), rl.path)
+		return
+	}
+
+	old := rl.cluster
+	rl.cluster = c
+	changes := 0
+	changed := func(format string, v ...interface{}) {
+		changes++
+		rl.logger.WarningPrintf("CLUSTER FILE CHANGED: %v", fmt.Sprintf(format, v...))
+	}
+
+	if old.Log.Level != c.Log.Level {
+		changed("log.level %q -> %q%v", old.Log.Level, c.Log.Level, rl.overridden("loglevel"))
+		if !rl.explicit["loglevel"] {
+			level, _ := utils.ParseLevel(c.Log.Level)
+			rl.logger.SetLevel(level)
+		}
+	}
+
+	if old.Hold != c.Hold {
+		changed("hold %q -> %q%v", old.Hold, c.Hold, rl.overridden("hold"))
+		if c.Hold != "" && !rl.explicit["hold"] {
+			hold, _ := time.ParseDuration(c.Hold)
+			rl.w.setHold(hold)
+		}
+	}
+
+	restart := func(field string, from interface{}, to interface{}) {
+		if from != to {
+			changed("%v %v -> %v (needs a restart)", field, from, to)
+		}
+	}
+	self, _ := old.node(rl.m.Name())
+	newSelf, _ := c.node(rl.m.Name())
+	restart("algorithm", old.Algorithm, c.Algorithm)
+	restart("k", old.K, c.K)
+	restart("epoch", old.Epoch, c.Epoch)
+	restart("coordinator", old.Coordinator, c.Coordinator)
+	restart("resource", old.Resource, c.Resource)
+	restart("ca", old.CA, c.CA)
+	if old.Secret != c.Secret {
+		changed("secret (needs a restart)")
+	}
+	restart("log.dir", old.Log.Dir, c.Log.Dir)
+	restart("log.delete", old.Log.Delete, c.Log.Delete)
+	restart("address", self.ipAddress(), newSelf.ipAddress())
+	restart("delay", self.Delay, newSelf.Delay)
+	restart("parent", self.Parent, newSelf.Parent)
+	restart("wal", self.WAL, newSelf.WAL)
+	restart("cert", self.Cert, newSelf.Cert)
+	restart("key", self.Key, newSelf.Key)
+	restart("metrics", self.Metrics, newSelf.Metrics)
+	restart("trace", self.Trace, newSelf.Trace)
+
+	var removed, added []clusterNode
+	for _, n := range old.Nodes {
+		if n.Name == rl.m.Name() {
+			continue
+		}
+		if m, ok := c.node(n.Name); !ok || m.ipAddress() != n.ipAddress() {
+			changed("node %v at %v removed", n.Name, n.ipAddress())
+			removed = append(removed, n)
+		}
+	}
+	for _, n := range c.Nodes {
+		if n.Name == rl.m.Name() {
+			continue
+		}
+		if m, ok := old.node(n.Name); !ok || m.ipAddress() != n.ipAddress() {
+			changed("node %v at %v added", n.Name, n.ipAddress())
+			added = append(added, n)
+		}
+	}
+
+	if changes == 0 {
+		rl.logger.InfoPrintf("The cluster file %v has not changed.\n", rl.path)
+		return
+	}
+
+	if len(removed) > 0 || len(added) > 0 {
+		if rl.w.whenReleased(func() { rl.changePeers(removed, added) }) {
+			rl.logger.WarningPrintf("%v is not RELEASED. Changing the peers when it is.", rl.m.Name())
+		}
+	}
+}
+
+// overridden returns a note for the log if the flag f is given on the command line, so its change in the cluster file is ignored.
+func (rl *reloader) overridden(f string) string {
+	if rl.explicit[f] {
+		return fmt.Sprintf(" (ignored, -%v is given on the command line)", f)
+	}
+	return ""
+}
+
+// changePeers removes the removed nodes from the peers of the node and adds the added ones.
+func (rl *reloader) changePeers(removed []clusterNode, added []clusterNode) {
+	for _, n := range removed {
+		if _, err := rl.m.RemovePeer(n.Name); err != nil {
+			rl.logger.ErrorPrintf("Could not remove %v from the peers. Restart the node instead. :: %v\n", n.Name, err)
+		}
+	}
+
+	for _, n := range added {
+		ctx, cancel := context.WithTimeout(context.Background(), addPeerTimeout)
+		name, err := rl.m.AddPeer(ctx, n.ipAddress())
+		cancel()
+
+		if err != nil {
+			rl.logger.ErrorPrintf("Could not add %v at %v to the peers. :: %v\n", n.Name, n.ipAddress(), err)
+			continue
+		}
+		if name != n.Name {
+			rl.logger.WarningPrintf("The node at %v is named %v, not %v as in the cluster file.", n.ipAddress(), name, n.Name)
+		}
+		rl.logger.WarningPrintf("%v added %v to the peers.", rl.m.Name(), name)
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"mandatory-exercise-2/dme"
+	"mandatory-exercise-2/utils"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestReloadExplicitFlags checks that reloading the cluster file changes the log level and the hold time,
+// unless they are given on the command line, like when the node starts.
+func TestReloadExplicitFlags(t *testing.T) {
+	path := writeCluster(t, "cluster.yaml", clusterYAML)
+	fs, values := testFlags(t, "-loglevel", "error")
+	explicit := explicitFlags(fs)
+	c, err := applyCluster(fs, path, "node1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger := utils.NewLoggerIn(t.TempDir(), "node1")
+	level, _ := utils.ParseLevel(*values["loglevel"].(*string))
+	logger.SetLevel(level)
+	w := newWorkload(1, 0, FIXED, *values["hold"].(*time.Duration), false)
+	m := dme.NewMutex(dme.Config{Name: "node1", Address: "127.0.0.1", Logger: logger})
+	rl := newReloader(path, c, m, logger, w, explicit)
+
+	changed := strings.NewReplacer("level: warning", "level: info", "hold: 500ms", "hold: 2s").Replace(clusterYAML)
+	if err := os.WriteFile(path, []byte(changed), 0644); err != nil {
+		t.Fatal(err)
+	}
+	rl.reload()
+
+	if got := logger.Level(); got != utils.ERROR {
+		t.Errorf("the log level is %v after the reload, want %v from the command line", got, utils.ERROR)
+	}
+	if got := w.holdTime(); got != 2*time.Second {
+		t.Errorf("the hold time is %v after the reload, want 2s from the file", got)
+	}
+}
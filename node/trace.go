@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"mandatory-exercise-2/utils"
+	"os"
+	"time"
+)
+
+// shutdownTimeout bounds the time to export the remaining spans when the node exits.
+const shutdownTimeout = 5 * time.Second
+
+// startTracing exports the spans of the node name to the file at path, one JSON object per span, appending to the file.
+// It returns the tracer provider of the node, and a function which exports the remaining spans and closes the file.
+func startTracing(path string, name string, logger *utils.Logger) (trace.TracerProvider, func()) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		logger.ErrorFatalf("Could not open the trace file %v. :: %v", path, err)
+	}
+
+	exporter, err := stdouttrace.New(stdouttrace.WithWriter(file))
+	if err != nil {
+		logger.ErrorFatalf("Could not create the trace exporter. :: %v", err)
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", name))),
+	)
+	logger.WarningPrintf("%v EXPORTS ITS TRACES TO %v.", name, path)
+
+	return tp, func() {
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := tp.Shutdown(ctx); err != nil {
+			logger.ErrorPrintf("Could not export the remaining spans. :: %v\n", err)
+		}
+		_ = file.Close()
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Think-time distributions of a workload.
+const (
+	FIXED       = "fixed"
+	UNIFORM     = "uniform"
+	EXPONENTIAL = "exponential"
+)
+
+// A workload describes how often a node requests the critical section and for how long it holds it.
+type workload struct {
+	count    int           // count is the number of times to enter the critical section. 0 means forever.
+	interval time.Duration // interval is the (mean) think time between leaving and requesting the critical section again.
+	think    string        // think is the distribution of the think time.
+	read     bool          // read is true if the critical section is entered for reading.
+	mu       sync.Mutex    // mu guards hold, busy, applying and delayed, which change while the workload runs.
+	applied  *sync.Cond    // applied is signalled when the changes being applied are done.
+	hold     time.Duration // hold is the time spent in the critical section at each entry.
+	busy     bool          // busy is true while the node is WANTED or HELD.
+	applying bool          // applying is true while changes are applied. The node is not entered meanwhile.
+	delayed  []func()      // delayed are the changes to apply when the node is RELEASED again.
+}
+
+// newWorkload creates a workload which enters the critical section count times and holds it for hold at each entry.
+func newWorkload(count int, interval time.Duration, think string, hold time.Duration, read bool) *workload {
+	w := &workload{count: count, interval: interval, think: think, hold: hold, read: read}
+	w.applied = sync.NewCond(&w.mu)
+	return w
+}
+
+// holdTime returns the time to hold the critical section at the next entry.
+func (w *workload) holdTime() time.Duration {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.hold
+}
+
+// setHold changes the time to hold the critical section from the next entry on.
+func (w *workload) setHold(hold time.Duration) {
+	w.mu.Lock()
+	w.hold = hold
+	w.mu.Unlock()
+}
+
+// enter marks the node WANTED, once the changes being applied, if any, are done.
+func (w *workload) enter() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for w.applying {
+		w.applied.Wait()
+	}
+	w.busy = true
+}
+
+// exit marks the node RELEASED, and applies the changes delayed until then.
+func (w *workload) exit() {
+	w.mu.Lock()
+	w.busy = false
+	delayed := w.delayed
+	w.delayed = nil
+	w.applying = len(delayed) > 0
+	w.mu.Unlock()
+
+	w.apply(delayed)
+}
+
+// whenReleased applies the change f at once if the node is RELEASED, and otherwise when it is RELEASED again.
+// It reports whether f was delayed.
+func (w *workload) whenReleased(f func()) bool {
+	w.mu.Lock()
+	for w.applying {
+		w.applied.Wait()
+	}
+	if w.busy {
+		w.delayed = append(w.delayed, f)
+		w.mu.Unlock()
+		return true
+	}
+	w.applying = true
+	w.mu.Unlock()
+
+	w.apply([]func(){f})
+	return false
+}
+
+// apply runs the changes fs, for which applying has been set, and then lets the node be entered again.
+// The changes run without holding mu, as adding a peer may block, and must not stall holdTime or setHold.
+func (w *workload) apply(fs []func()) {
+	if len(fs) == 0 {
+		return
+	}
+
+	for _, f := range fs {
+		f()
+	}
+
+	w.mu.Lock()
+	w.applying = false
+	w.applied.Broadcast()
+	w.mu.Unlock()
+}
+
+// thinkTime returns the time to wait before the next request, drawn from the workload's distribution.
+func (w *workload) thinkTime() time.Duration {
+	switch w.think {
+	case UNIFORM:
+		if w.interval <= 0 {
+			return 0
+		}
+		return time.Duration(rand.Int63n(2 * int64(w.interval)))
+	case EXPONENTIAL:
+		return time.Duration(rand.ExpFloat64() * float64(w.interval))
+	default:
+		return w.interval
+	}
+}
+
+// more reports whether another round should be run after round rounds have completed.
+func (w *workload) more(round int) bool {
+	return w.count == 0 || round < w.count
+}
+
+// validThink reports whether think is a known think-time distribution.
+func validThink(think string) bool {
+	return think == FIXED || think == UNIFORM || think == EXPONENTIAL
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// slowChange returns a change which takes a while to apply, and records in applying whether it is running.
+func slowChange(applying *int32) func() {
+	return func() {
+		atomic.StoreInt32(applying, 1)
+		time.Sleep(100 * time.Millisecond)
+		atomic.StoreInt32(applying, 0)
+	}
+}
+
+// enterDuring calls enter as soon as the change recording in applying has started,
+// and fails the test if enter returns before the change is done.
+func enterDuring(t *testing.T, w *workload, applying *int32) chan struct{} {
+	t.Helper()
+
+	entered := make(chan struct{})
+	go func() {
+		defer close(entered)
+		for atomic.LoadInt32(applying) == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		w.enter()
+		if atomic.LoadInt32(applying) != 0 {
+			t.Error("the node was entered while a change was applied")
+		}
+	}()
+	return entered
+}
+
+// TestWhenReleased checks that a change made while the node is RELEASED is applied at once,
+// and that the node is not entered until it is done.
+func TestWhenReleased(t *testing.T) {
+	w := newWorkload(1, 0, FIXED, 0, false)
+
+	var applying int32
+	entered := enterDuring(t, w, &applying)
+	if w.whenReleased(slowChange(&applying)) {
+		t.Error("a change was delayed while the node is RELEASED")
+	}
+	<-entered
+}
+
+// TestWhenReleasedDelayed checks that a change made while the node is WANTED or HELD is delayed until it is RELEASED,
+// and that the node is not entered again until it is done.
+func TestWhenReleasedDelayed(t *testing.T) {
+	w := newWorkload(1, 0, FIXED, 0, false)
+	w.enter()
+
+	var applying int32
+	if !w.whenReleased(slowChange(&applying)) {
+		t.Fatal("a change was applied while the node is WANTED")
+	}
+	if atomic.LoadInt32(&applying) != 0 {
+		t.Fatal("a delayed change was applied before the node is RELEASED")
+	}
+
+	entered := enterDuring(t, w, &applying)
+	w.exit()
+	<-entered
+}
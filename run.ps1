@@ -1,38 +1,13 @@
-param([Int32]$n=3)
-#Run with -nodes <number of nodes>
+param([String]$config='cluster.json')
+#Run with -config <cluster file>. Starts every node of the cluster file.
 
-$names = @('node0', 'node1', 'node2', 'node3', 'node4')
-$sports = @('8080', '8081', '8082', '8083', '8084')
-$ips = ''
-$delays = @(5, 5, 5, 5, 5)
+$path = (Resolve-Path $config).Path
+$cluster = Get-Content $path -Raw | ConvertFrom-Json
 
-for ($i = 0; $i -lt $n; $i++) {
-    $ips = ''
-    if ($i -lt $names.count) {
-        $name = $names[$i]
-        $sport = $sports[$i]
-        $delay = $delays[$i]
-        
-        for ($j = 0; $j -lt $n; $j++) {
-            
-            if ($n -ne 1) {
-                if ($sports[$j] -eq $sport) {
-                    continue
-                }
-            }
+foreach ($node in $cluster.nodes) {
+    $name = $node.name
 
-            if ($ips -eq '') {
-                $ips += $sports[$j]
-            } else {
-                $ips += ',' + $sports[$j]
-            }
-        }
-    } else {
-        "WARNING: Max number of nodes for this script has been created (" + $names.count + "). => Not creating any more nodes!"
-        break
-    }
-
-    "STARTING GO => $name | Server port: $sport | IPs: $ips | Delay: $delay |"
+    "STARTING GO => $name | Server port: " + $node.port + " | Delay: " + $node.delay + " |"
 
     $Command = 'cmd /c start powershell -NoExit -Command {
             $host.UI.RawUI.WindowTitle = "Node - ' + $name + '";
@@ -40,8 +15,8 @@ for ($i = 0; $i -lt $n; $i++) {
             $host.UI.RawUI.ForegroundColor = "white";
             Clear-Host;
             cd node;
-            go run . -name ' + $name + ' -sport ' + $sport + ' -ips ' + $ips + ' -delay ' + $delay + ';
+            go run . -config "' + $path + '" -name ' + $name + ';
         }'
 
     invoke-expression -Command $Command
-}
\ No newline at end of file
+}
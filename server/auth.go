@@ -0,0 +1,115 @@
+package server
+
+import (
+	"context"
+	"crypto/hmac"
+	"errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"mandatory-exercise-2/utils"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// MAX_CLOCK_SKEW is the longest time between signing an RPC and receiving it, in either direction, for the RPC to be accepted.
+const MAX_CLOCK_SKEW = 30 * time.Second
+
+// An authenticator checks the signature of every RPC to a Server, and rejects RPCs which are not signed with the secret of the cluster.
+type authenticator struct {
+	secret  []byte               // secret is the secret of the cluster.
+	address string               // address is the address of the server, which RPCs must be signed for.
+	logger  *utils.Logger        // logger logs every rejected RPC.
+	mu      sync.Mutex           // mu guards all the fields below.
+	seen    map[string]time.Time // seen is the signing time of every nonce accepted within the last MAX_CLOCK_SKEW, by nonce.
+	pruned  time.Time            // pruned is the last time seen was pruned.
+}
+
+// WithSecret returns a server option which rejects every RPC which is not signed with the secret of the cluster by a client
+// dialed with client.WithSecret at the address of the server, or whose signature is stale or has been used before.
+// Every rejection is logged.
+func WithSecret(secret []byte, address string, logger *utils.Logger) grpc.ServerOption {
+	a := &authenticator{secret: secret, address: address, logger: logger, seen: make(map[string]time.Time), pruned: time.Now()}
+	return grpc.ChainUnaryInterceptor(a.intercept)
+}
+
+// intercept handles the RPC to info.FullMethod if it is authentic.
+func (a *authenticator) intercept(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if err := a.verify(ctx, info.FullMethod, req); err != nil {
+		from := "unknown"
+		if p, ok := peer.FromContext(ctx); ok {
+			from = p.Addr.String()
+		}
+		a.logger.WarningPrintf("REJECTED %v FROM %v. :: %v", info.FullMethod, from, err)
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+
+	return handler(ctx, req)
+}
+
+// verify returns an error unless the RPC to method with the request req is signed with the secret, for the server's address,
+// recently, and with a fresh nonce.
+func (a *authenticator) verify(ctx context.Context, method string, req interface{}) error {
+	md, _ := metadata.FromIncomingContext(ctx)
+	timestamp, nonce, signature := first(md, utils.AUTH_TIMESTAMP), first(md, utils.AUTH_NONCE), first(md, utils.AUTH_SIGNATURE)
+	if timestamp == "" || nonce == "" || signature == "" {
+		return errors.New("missing credentials")
+	}
+
+	m, ok := req.(proto.Message)
+	if !ok {
+		return errors.New("unsigned request type")
+	}
+	request, err := proto.MarshalOptions{Deterministic: true}.Marshal(m)
+	if err != nil {
+		return err
+	}
+	if !hmac.Equal([]byte(signature), []byte(utils.Sign(a.secret, method, a.address, timestamp, nonce, request))) {
+		return errors.New("bad signature")
+	}
+
+	nanos, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return errors.New("bad timestamp")
+	}
+	signed := time.Unix(0, nanos)
+	if skew := time.Since(signed); skew > MAX_CLOCK_SKEW || skew < -MAX_CLOCK_SKEW {
+		return errors.New("stale signature, signed " + skew.Round(time.Millisecond).String() + " ago")
+	}
+
+	return a.use(nonce, signed)
+}
+
+// use records the nonce of an RPC signed at the given time, and returns an error if it has been used before.
+func (a *authenticator) use(nonce string, signed time.Time) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(a.pruned) > MAX_CLOCK_SKEW {
+		for n, t := range a.seen {
+			if now.Sub(t) > MAX_CLOCK_SKEW {
+				delete(a.seen, n)
+			}
+		}
+		a.pruned = now
+	}
+
+	if _, ok := a.seen[nonce]; ok {
+		return errors.New("replayed nonce")
+	}
+	a.seen[nonce] = signed
+	return nil
+}
+
+// first returns the first value of the metadata key, or "" if it has none.
+func first(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
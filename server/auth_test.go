@@ -0,0 +1,122 @@
+package server
+
+import (
+	"context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"mandatory-exercise-2/service"
+	"mandatory-exercise-2/utils"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// METHOD is the full method name of the RPCs of the tests.
+const METHOD = "/Service.Service/Publish"
+
+// ADDRESS is the address of the server of the tests.
+const ADDRESS = "127.0.0.1:8080"
+
+// newTestAuthenticator creates an authenticator at ADDRESS with the secret and a logger in a temporary directory.
+func newTestAuthenticator(t *testing.T, secret string) *authenticator {
+	t.Helper()
+
+	logger := utils.NewLoggerIn(t.TempDir(), "auth")
+	logger.SetLevel(utils.ERROR)
+	return &authenticator{secret: []byte(secret), address: ADDRESS, logger: logger, seen: make(map[string]time.Time), pruned: time.Now()}
+}
+
+// sign returns an incoming context with the credentials of the request req to METHOD of the server at ADDRESS,
+// signed with secret at the given time with the nonce.
+func sign(t *testing.T, secret string, req *service.Request, signed time.Time, nonce string) context.Context {
+	t.Helper()
+
+	return signFor(t, secret, ADDRESS, req, signed, nonce)
+}
+
+// signFor is sign for the server at the address destination.
+func signFor(t *testing.T, secret string, destination string, req *service.Request, signed time.Time, nonce string) context.Context {
+	t.Helper()
+
+	request, err := proto.MarshalOptions{Deterministic: true}.Marshal(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	timestamp := strconv.FormatInt(signed.UnixNano(), 10)
+
+	return metadata.NewIncomingContext(context.Background(), metadata.Pairs(
+		utils.AUTH_TIMESTAMP, timestamp,
+		utils.AUTH_NONCE, nonce,
+		utils.AUTH_SIGNATURE, utils.Sign([]byte(secret), METHOD, destination, timestamp, nonce, request),
+	))
+}
+
+// TestVerify checks that only RPCs signed with the secret of the cluster, recently and for the request they carry, are accepted.
+func TestVerify(t *testing.T) {
+	req := &service.Request{Name: "node1", Lamport: 7}
+	for _, test := range []struct {
+		name string
+		ctx  context.Context
+		req  *service.Request
+		err  string
+	}{
+		{"signed", sign(t, "secret", req, time.Now(), "n1"), req, ""},
+		{"unsigned", context.Background(), req, "missing credentials"},
+		{"another secret", sign(t, "guess", req, time.Now(), "n2"), req, "bad signature"},
+		{"another request", sign(t, "secret", req, time.Now(), "n3"), &service.Request{Name: "node1", Lamport: 8}, "bad signature"},
+		{"another node", signFor(t, "secret", "127.0.0.1:8081", req, time.Now(), "n6"), req, "bad signature"},
+		{"the same node by port", signFor(t, "secret", "127.0.0.1:08080", req, time.Now(), "n7"), req, ""},
+		{"signed too long ago", sign(t, "secret", req, time.Now().Add(-2*MAX_CLOCK_SKEW), "n4"), req, "stale signature"},
+		{"signed ahead", sign(t, "secret", req, time.Now().Add(2*MAX_CLOCK_SKEW), "n5"), req, "stale signature"},
+	} {
+		err := newTestAuthenticator(t, "secret").verify(test.ctx, METHOD, test.req)
+		if test.err == "" && err != nil {
+			t.Errorf("%v: rejected: %v", test.name, err)
+		} else if test.err != "" && (err == nil || !strings.Contains(err.Error(), test.err)) {
+			t.Errorf("%v: got %v, want %q", test.name, err, test.err)
+		}
+	}
+}
+
+// TestReplay checks that a signed RPC is accepted once, and rejected when it is replayed.
+func TestReplay(t *testing.T) {
+	a := newTestAuthenticator(t, "secret")
+	req := &service.Request{Name: "node1", Lamport: 7}
+	ctx := sign(t, "secret", req, time.Now(), "nonce")
+
+	if err := a.verify(ctx, METHOD, req); err != nil {
+		t.Fatalf("the first RPC was rejected: %v", err)
+	}
+	if err := a.verify(ctx, METHOD, req); err == nil || !strings.Contains(err.Error(), "replayed nonce") {
+		t.Errorf("the replayed RPC got %v, want a replayed nonce", err)
+	}
+	if err := a.verify(sign(t, "secret", req, time.Now(), "another nonce"), METHOD, req); err != nil {
+		t.Errorf("the same request with a fresh nonce was rejected: %v", err)
+	}
+}
+
+// TestIntercept checks that a rejected RPC never reaches its handler, and fails with codes.Unauthenticated.
+func TestIntercept(t *testing.T) {
+	a := newTestAuthenticator(t, "secret")
+	req := &service.Request{Name: "node1"}
+	handled := 0
+	handler := func(context.Context, interface{}) (interface{}, error) {
+		handled++
+		return &service.Reply{}, nil
+	}
+	info := &grpc.UnaryServerInfo{FullMethod: METHOD}
+
+	if _, err := a.intercept(context.Background(), req, info, handler); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("the unsigned RPC got %v, want Unauthenticated", err)
+	}
+	if _, err := a.intercept(sign(t, "secret", req, time.Now(), "nonce"), req, info, handler); err != nil {
+		t.Errorf("the signed RPC got %v", err)
+	}
+	if handled != 1 {
+		t.Errorf("the handler handled %v RPCs, want only the signed one", handled)
+	}
+}
@@ -45,9 +45,10 @@ func (s *Server) Stop() {
 }
 
 // NewServer creates and returns a new Server which will be run on a specific ip address.
-func NewServer(logger *utils.Logger) *Server {
+// The options opts configure the gRPC server, e.g. its transport credentials.
+func NewServer(logger *utils.Logger, opts ...grpc.ServerOption) *Server {
 	return &Server{
-		grpcServer: grpc.NewServer(),
+		grpcServer: grpc.NewServer(opts...),
 		logger:     logger,
 	}
 }
@@ -0,0 +1,34 @@
+package server
+
+import (
+	"context"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"mandatory-exercise-2/utils"
+)
+
+// WithTracing returns a server option which continues the trace of every RPC from a client dialed with client.WithTracing:
+// the RPC is handled in a span of the tracer provider tp, named by its method, which is a child of the caller's span.
+// RPCs without a trace context, e.g. heartbeats, are not traced.
+func WithTracing(tp trace.TracerProvider) grpc.ServerOption {
+	tracer := tp.Tracer("mandatory-exercise-2/server")
+	return grpc.ChainUnaryInterceptor(func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		ctx = propagation.TraceContext{}.Extract(ctx, utils.MetadataCarrier(md))
+		if !trace.SpanContextFromContext(ctx).IsValid() {
+			return handler(ctx, req)
+		}
+
+		ctx, span := tracer.Start(ctx, info.FullMethod, trace.WithSpanKind(trace.SpanKindServer))
+		defer span.End()
+
+		reply, err := handler(ctx, req)
+		if err != nil {
+			span.SetStatus(codes.Error, err.Error())
+		}
+		return reply, err
+	})
+}
@@ -21,6 +21,33 @@ type ServiceClient interface {
 	Publish(ctx context.Context, in *Request, opts ...grpc.CallOption) (*Reply, error)
 	ReplySender(ctx context.Context, in *Request, opts ...grpc.CallOption) (*Reply, error)
 	GetName(ctx context.Context, in *NameRequest, opts ...grpc.CallOption) (*NameReply, error)
+	// Membership. A joining node joins every member with Join, and a leaving node leaves every member with Leave.
+	Join(ctx context.Context, in *NameRequest, opts ...grpc.CallOption) (*JoinReply, error)
+	Leave(ctx context.Context, in *Request, opts ...grpc.CallOption) (*Reply, error)
+	// Gossip. A node probes a random member with Ping, or, if it does not answer, asks other members to probe it with PingReq.
+	Ping(ctx context.Context, in *Gossip, opts ...grpc.CallOption) (*Gossip, error)
+	PingReq(ctx context.Context, in *Gossip, opts ...grpc.CallOption) (*Gossip, error)
+	// Maekawa voting. The lamport field of each message is the timestamp of the request it refers to.
+	Request(ctx context.Context, in *Request, opts ...grpc.CallOption) (*Reply, error)
+	Locked(ctx context.Context, in *Request, opts ...grpc.CallOption) (*Reply, error)
+	Failed(ctx context.Context, in *Request, opts ...grpc.CallOption) (*Reply, error)
+	Inquire(ctx context.Context, in *Request, opts ...grpc.CallOption) (*Reply, error)
+	Relinquish(ctx context.Context, in *Request, opts ...grpc.CallOption) (*Reply, error)
+	Release(ctx context.Context, in *Request, opts ...grpc.CallOption) (*Reply, error)
+	// Token based algorithms. A token request is sent with Publish.
+	PassToken(ctx context.Context, in *Token, opts ...grpc.CallOption) (*Reply, error)
+	// Leases. A node deferring requests renews its lease at the requesters with Renew.
+	Renew(ctx context.Context, in *Request, opts ...grpc.CallOption) (*Reply, error)
+	// Failure detector. A node sends a heartbeat to every peer with Heartbeat.
+	Heartbeat(ctx context.Context, in *Request, opts ...grpc.CallOption) (*Reply, error)
+	// Cancellation. A node giving up a request tells the peers it sent the request to to forget it with Cancel.
+	// The lamport field (or the sequence field of the centralized coordinator) is that of the request.
+	Cancel(ctx context.Context, in *Request, opts ...grpc.CallOption) (*Reply, error)
+	// Crash recovery. A node restarted from its write-ahead log tells every peer with Recover to forget its requests
+	// up to the lamport field, and learns the peer's Lamport clock from the reply.
+	Recover(ctx context.Context, in *Request, opts ...grpc.CallOption) (*Reply, error)
+	// Centralized coordinator. A lock request is sent with Publish, granted with ReplySender and released with Release.
+	GetQueue(ctx context.Context, in *QueueRequest, opts ...grpc.CallOption) (*QueueReply, error)
 }
 
 type serviceClient struct {
@@ -58,6 +85,150 @@ func (c *serviceClient) GetName(ctx context.Context, in *NameRequest, opts ...gr
 	return out, nil
 }
 
+func (c *serviceClient) Join(ctx context.Context, in *NameRequest, opts ...grpc.CallOption) (*JoinReply, error) {
+	out := new(JoinReply)
+	err := c.cc.Invoke(ctx, "/Service.Service/Join", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *serviceClient) Leave(ctx context.Context, in *Request, opts ...grpc.CallOption) (*Reply, error) {
+	out := new(Reply)
+	err := c.cc.Invoke(ctx, "/Service.Service/Leave", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *serviceClient) Ping(ctx context.Context, in *Gossip, opts ...grpc.CallOption) (*Gossip, error) {
+	out := new(Gossip)
+	err := c.cc.Invoke(ctx, "/Service.Service/Ping", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *serviceClient) PingReq(ctx context.Context, in *Gossip, opts ...grpc.CallOption) (*Gossip, error) {
+	out := new(Gossip)
+	err := c.cc.Invoke(ctx, "/Service.Service/PingReq", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *serviceClient) Request(ctx context.Context, in *Request, opts ...grpc.CallOption) (*Reply, error) {
+	out := new(Reply)
+	err := c.cc.Invoke(ctx, "/Service.Service/Request", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *serviceClient) Locked(ctx context.Context, in *Request, opts ...grpc.CallOption) (*Reply, error) {
+	out := new(Reply)
+	err := c.cc.Invoke(ctx, "/Service.Service/Locked", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *serviceClient) Failed(ctx context.Context, in *Request, opts ...grpc.CallOption) (*Reply, error) {
+	out := new(Reply)
+	err := c.cc.Invoke(ctx, "/Service.Service/Failed", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *serviceClient) Inquire(ctx context.Context, in *Request, opts ...grpc.CallOption) (*Reply, error) {
+	out := new(Reply)
+	err := c.cc.Invoke(ctx, "/Service.Service/Inquire", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *serviceClient) Relinquish(ctx context.Context, in *Request, opts ...grpc.CallOption) (*Reply, error) {
+	out := new(Reply)
+	err := c.cc.Invoke(ctx, "/Service.Service/Relinquish", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *serviceClient) Release(ctx context.Context, in *Request, opts ...grpc.CallOption) (*Reply, error) {
+	out := new(Reply)
+	err := c.cc.Invoke(ctx, "/Service.Service/Release", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *serviceClient) PassToken(ctx context.Context, in *Token, opts ...grpc.CallOption) (*Reply, error) {
+	out := new(Reply)
+	err := c.cc.Invoke(ctx, "/Service.Service/PassToken", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *serviceClient) Renew(ctx context.Context, in *Request, opts ...grpc.CallOption) (*Reply, error) {
+	out := new(Reply)
+	err := c.cc.Invoke(ctx, "/Service.Service/Renew", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *serviceClient) Heartbeat(ctx context.Context, in *Request, opts ...grpc.CallOption) (*Reply, error) {
+	out := new(Reply)
+	err := c.cc.Invoke(ctx, "/Service.Service/Heartbeat", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *serviceClient) Cancel(ctx context.Context, in *Request, opts ...grpc.CallOption) (*Reply, error) {
+	out := new(Reply)
+	err := c.cc.Invoke(ctx, "/Service.Service/Cancel", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *serviceClient) Recover(ctx context.Context, in *Request, opts ...grpc.CallOption) (*Reply, error) {
+	out := new(Reply)
+	err := c.cc.Invoke(ctx, "/Service.Service/Recover", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *serviceClient) GetQueue(ctx context.Context, in *QueueRequest, opts ...grpc.CallOption) (*QueueReply, error) {
+	out := new(QueueReply)
+	err := c.cc.Invoke(ctx, "/Service.Service/GetQueue", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // ServiceServer is the server API for Service service.
 // All implementations must embed UnimplementedServiceServer
 // for forward compatibility
@@ -65,6 +236,33 @@ type ServiceServer interface {
 	Publish(context.Context, *Request) (*Reply, error)
 	ReplySender(context.Context, *Request) (*Reply, error)
 	GetName(context.Context, *NameRequest) (*NameReply, error)
+	// Membership. A joining node joins every member with Join, and a leaving node leaves every member with Leave.
+	Join(context.Context, *NameRequest) (*JoinReply, error)
+	Leave(context.Context, *Request) (*Reply, error)
+	// Gossip. A node probes a random member with Ping, or, if it does not answer, asks other members to probe it with PingReq.
+	Ping(context.Context, *Gossip) (*Gossip, error)
+	PingReq(context.Context, *Gossip) (*Gossip, error)
+	// Maekawa voting. The lamport field of each message is the timestamp of the request it refers to.
+	Request(context.Context, *Request) (*Reply, error)
+	Locked(context.Context, *Request) (*Reply, error)
+	Failed(context.Context, *Request) (*Reply, error)
+	Inquire(context.Context, *Request) (*Reply, error)
+	Relinquish(context.Context, *Request) (*Reply, error)
+	Release(context.Context, *Request) (*Reply, error)
+	// Token based algorithms. A token request is sent with Publish.
+	PassToken(context.Context, *Token) (*Reply, error)
+	// Leases. A node deferring requests renews its lease at the requesters with Renew.
+	Renew(context.Context, *Request) (*Reply, error)
+	// Failure detector. A node sends a heartbeat to every peer with Heartbeat.
+	Heartbeat(context.Context, *Request) (*Reply, error)
+	// Cancellation. A node giving up a request tells the peers it sent the request to to forget it with Cancel.
+	// The lamport field (or the sequence field of the centralized coordinator) is that of the request.
+	Cancel(context.Context, *Request) (*Reply, error)
+	// Crash recovery. A node restarted from its write-ahead log tells every peer with Recover to forget its requests
+	// up to the lamport field, and learns the peer's Lamport clock from the reply.
+	Recover(context.Context, *Request) (*Reply, error)
+	// Centralized coordinator. A lock request is sent with Publish, granted with ReplySender and released with Release.
+	GetQueue(context.Context, *QueueRequest) (*QueueReply, error)
 	mustEmbedUnimplementedServiceServer()
 }
 
@@ -81,6 +279,54 @@ func (UnimplementedServiceServer) ReplySender(context.Context, *Request) (*Reply
 func (UnimplementedServiceServer) GetName(context.Context, *NameRequest) (*NameReply, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetName not implemented")
 }
+func (UnimplementedServiceServer) Join(context.Context, *NameRequest) (*JoinReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Join not implemented")
+}
+func (UnimplementedServiceServer) Leave(context.Context, *Request) (*Reply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Leave not implemented")
+}
+func (UnimplementedServiceServer) Ping(context.Context, *Gossip) (*Gossip, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Ping not implemented")
+}
+func (UnimplementedServiceServer) PingReq(context.Context, *Gossip) (*Gossip, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PingReq not implemented")
+}
+func (UnimplementedServiceServer) Request(context.Context, *Request) (*Reply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Request not implemented")
+}
+func (UnimplementedServiceServer) Locked(context.Context, *Request) (*Reply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Locked not implemented")
+}
+func (UnimplementedServiceServer) Failed(context.Context, *Request) (*Reply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Failed not implemented")
+}
+func (UnimplementedServiceServer) Inquire(context.Context, *Request) (*Reply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Inquire not implemented")
+}
+func (UnimplementedServiceServer) Relinquish(context.Context, *Request) (*Reply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Relinquish not implemented")
+}
+func (UnimplementedServiceServer) Release(context.Context, *Request) (*Reply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Release not implemented")
+}
+func (UnimplementedServiceServer) PassToken(context.Context, *Token) (*Reply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PassToken not implemented")
+}
+func (UnimplementedServiceServer) Renew(context.Context, *Request) (*Reply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Renew not implemented")
+}
+func (UnimplementedServiceServer) Heartbeat(context.Context, *Request) (*Reply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Heartbeat not implemented")
+}
+func (UnimplementedServiceServer) Cancel(context.Context, *Request) (*Reply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Cancel not implemented")
+}
+func (UnimplementedServiceServer) Recover(context.Context, *Request) (*Reply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Recover not implemented")
+}
+func (UnimplementedServiceServer) GetQueue(context.Context, *QueueRequest) (*QueueReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetQueue not implemented")
+}
 func (UnimplementedServiceServer) mustEmbedUnimplementedServiceServer() {}
 
 // UnsafeServiceServer may be embedded to opt out of forward compatibility for this service.
@@ -148,6 +394,294 @@ func _Service_GetName_Handler(srv interface{}, ctx context.Context, dec func(int
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Service_Join_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(NameRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ServiceServer).Join(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Service.Service/Join",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ServiceServer).Join(ctx, req.(*NameRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Service_Leave_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Request)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ServiceServer).Leave(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Service.Service/Leave",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ServiceServer).Leave(ctx, req.(*Request))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Service_Ping_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Gossip)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ServiceServer).Ping(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Service.Service/Ping",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ServiceServer).Ping(ctx, req.(*Gossip))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Service_PingReq_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Gossip)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ServiceServer).PingReq(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Service.Service/PingReq",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ServiceServer).PingReq(ctx, req.(*Gossip))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Service_Request_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Request)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ServiceServer).Request(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Service.Service/Request",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ServiceServer).Request(ctx, req.(*Request))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Service_Locked_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Request)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ServiceServer).Locked(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Service.Service/Locked",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ServiceServer).Locked(ctx, req.(*Request))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Service_Failed_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Request)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ServiceServer).Failed(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Service.Service/Failed",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ServiceServer).Failed(ctx, req.(*Request))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Service_Inquire_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Request)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ServiceServer).Inquire(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Service.Service/Inquire",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ServiceServer).Inquire(ctx, req.(*Request))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Service_Relinquish_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Request)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ServiceServer).Relinquish(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Service.Service/Relinquish",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ServiceServer).Relinquish(ctx, req.(*Request))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Service_Release_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Request)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ServiceServer).Release(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Service.Service/Release",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ServiceServer).Release(ctx, req.(*Request))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Service_PassToken_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Token)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ServiceServer).PassToken(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Service.Service/PassToken",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ServiceServer).PassToken(ctx, req.(*Token))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Service_Renew_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Request)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ServiceServer).Renew(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Service.Service/Renew",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ServiceServer).Renew(ctx, req.(*Request))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Service_Heartbeat_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Request)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ServiceServer).Heartbeat(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Service.Service/Heartbeat",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ServiceServer).Heartbeat(ctx, req.(*Request))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Service_Cancel_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Request)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ServiceServer).Cancel(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Service.Service/Cancel",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ServiceServer).Cancel(ctx, req.(*Request))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Service_Recover_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Request)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ServiceServer).Recover(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Service.Service/Recover",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ServiceServer).Recover(ctx, req.(*Request))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Service_GetQueue_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(QueueRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ServiceServer).GetQueue(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Service.Service/GetQueue",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ServiceServer).GetQueue(ctx, req.(*QueueRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // Service_ServiceDesc is the grpc.ServiceDesc for Service service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -167,6 +701,70 @@ var Service_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetName",
 			Handler:    _Service_GetName_Handler,
 		},
+		{
+			MethodName: "Join",
+			Handler:    _Service_Join_Handler,
+		},
+		{
+			MethodName: "Leave",
+			Handler:    _Service_Leave_Handler,
+		},
+		{
+			MethodName: "Ping",
+			Handler:    _Service_Ping_Handler,
+		},
+		{
+			MethodName: "PingReq",
+			Handler:    _Service_PingReq_Handler,
+		},
+		{
+			MethodName: "Request",
+			Handler:    _Service_Request_Handler,
+		},
+		{
+			MethodName: "Locked",
+			Handler:    _Service_Locked_Handler,
+		},
+		{
+			MethodName: "Failed",
+			Handler:    _Service_Failed_Handler,
+		},
+		{
+			MethodName: "Inquire",
+			Handler:    _Service_Inquire_Handler,
+		},
+		{
+			MethodName: "Relinquish",
+			Handler:    _Service_Relinquish_Handler,
+		},
+		{
+			MethodName: "Release",
+			Handler:    _Service_Release_Handler,
+		},
+		{
+			MethodName: "PassToken",
+			Handler:    _Service_PassToken_Handler,
+		},
+		{
+			MethodName: "Renew",
+			Handler:    _Service_Renew_Handler,
+		},
+		{
+			MethodName: "Heartbeat",
+			Handler:    _Service_Heartbeat_Handler,
+		},
+		{
+			MethodName: "Cancel",
+			Handler:    _Service_Cancel_Handler,
+		},
+		{
+			MethodName: "Recover",
+			Handler:    _Service_Recover_Handler,
+		},
+		{
+			MethodName: "GetQueue",
+			Handler:    _Service_GetQueue_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "service/service.proto",
@@ -0,0 +1,29 @@
+package utils
+
+import "strings"
+
+// A MetadataCarrier carries the trace context of an RPC in its gRPC metadata, e.g. metadata.MD.
+// It implements propagation.TextMapCarrier of OpenTelemetry.
+type MetadataCarrier map[string][]string
+
+// Get returns the first value of the key, or "" if it has none.
+func (c MetadataCarrier) Get(key string) string {
+	if values := c[strings.ToLower(key)]; len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// Set sets the value of the key. Keys of gRPC metadata are lowercase.
+func (c MetadataCarrier) Set(key string, value string) {
+	c[strings.ToLower(key)] = []string{value}
+}
+
+// Keys returns all keys of the carrier.
+func (c MetadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for key := range c {
+		keys = append(keys, key)
+	}
+	return keys
+}
@@ -28,15 +28,19 @@ func (l *Lamport) MaxAndIncrement(other int32) {
 
 // Value returns the value of the Lamport clock.
 func (l *Lamport) Value() int32 {
+	defer l.mu.Unlock()
+	l.mu.Lock()
 	return l.clockValue
 }
 
-func (l *Lamport) CompareLamportAndProcess(p1 string, t2 int32, p2 string) bool {
-	if l.clockValue < t2 {
+// Before reports whether the event (t1, p1) is ordered before the event (t2, p2),
+// i.e. t1 < t2, or t1 == t2 and the process name p1 < p2.
+func Before(t1 int32, p1 string, t2 int32, p2 string) bool {
+	if t1 < t2 {
 		return true
 	}
 
-	if l.clockValue == t2 {
+	if t1 == t2 {
 		if p1 < p2 {
 			return true
 		}
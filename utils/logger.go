@@ -1,13 +1,23 @@
 package utils
 
 import (
+	"fmt"
 	"log"
 	"os"
+	"strings"
+	"sync/atomic"
 )
 
 // logPath is the path to the output directory for the Logger.
 const logPath = "..\\logs\\"
 
+// Constants which represent the levels of a Logger. A Logger drops the calls below its level.
+const (
+	INFO    int32 = 0
+	WARNING       = 1
+	ERROR         = 2
+)
+
 // Logger is a log used to write log calls to a file.
 // It consists of three prefix loggers InfoLogger, WarningLogger and ErrorLogger,
 // which sets their respective prefix to the log file.
@@ -16,26 +26,39 @@ type Logger struct {
 	WarningLogger *log.Logger // WarningLogger adds the prefix "WARNING" to the log string at each call.
 	ErrorLogger   *log.Logger // ErrorLogger adds the prefix "ERROR" to the log string at each call.
 	file          *os.File
+	level         int32 // level is the level of the Logger. It is accessed atomically, so it can change while the Logger is in use.
 }
 
 // InfoPrintln Prints both to the log file and to the console.
 // Equals to calling logger.InfoLogger.Println() and a normal log.println()
 func (l *Logger) InfoPrintln(v ...interface{}) {
+	if l.Level() > INFO {
+		return
+	}
 	l.InfoLogger.Println(v...)
 	log.Println(v...)
 }
 
 func (l *Logger) InfoPrintf(format string, v ...interface{}) {
+	if l.Level() > INFO {
+		return
+	}
 	l.InfoLogger.Printf(format, v...)
 	log.Printf(format, v...)
 }
 
 func (l *Logger) WarningPrintln(v ...interface{}) {
+	if l.Level() > WARNING {
+		return
+	}
 	l.WarningLogger.Println(v...)
 	log.Println(v...)
 }
 
 func (l *Logger) WarningPrintf(format string, v ...interface{}) {
+	if l.Level() > WARNING {
+		return
+	}
 	l.WarningLogger.Printf(format, v...)
 	log.Printf(format, v...)
 }
@@ -50,6 +73,30 @@ func (l *Logger) ErrorFatalf(format string, v ...interface{}) {
 	log.Fatalf(format, v...)
 }
 
+// Level returns the level of the Logger.
+func (l *Logger) Level() int32 {
+	return atomic.LoadInt32(&l.level)
+}
+
+// SetLevel sets the level of the Logger: INFO, WARNING or ERROR.
+func (l *Logger) SetLevel(level int32) {
+	atomic.StoreInt32(&l.level, level)
+}
+
+// ParseLevel returns the level named by s: "info", "warning" or "error".
+func ParseLevel(s string) (int32, error) {
+	switch strings.ToLower(s) {
+	case "info":
+		return INFO, nil
+	case "warning":
+		return WARNING, nil
+	case "error":
+		return ERROR, nil
+	default:
+		return 0, fmt.Errorf("unknown log level %q", s)
+	}
+}
+
 // DeleteLog deletes the log file associated with this logger.
 func (l *Logger) DeleteLog() {
 	err := l.file.Close()
@@ -60,13 +107,26 @@ func (l *Logger) DeleteLog() {
 	_ = os.Remove(l.file.Name())
 }
 
-// NewLogger creates a new Logger and binds it to a file with the given filename.
+// NewLogger creates a new Logger and binds it to a file with the given filename in the default log directory.
 func NewLogger(filename string) *Logger {
-	if _, err := os.Stat(logPath); os.IsNotExist(err) {
-		_ = os.Mkdir(logPath, os.ModeDir)
+	return NewLoggerIn(logPath, filename)
+}
+
+// NewLoggerIn creates a new Logger and binds it to a file with the given filename in the directory dir.
+// An empty dir is the default log directory.
+func NewLoggerIn(dir string, filename string) *Logger {
+	if dir == "" {
+		dir = logPath
+	}
+	if !strings.HasSuffix(dir, "/") && !strings.HasSuffix(dir, "\\") {
+		dir += string(os.PathSeparator)
+	}
+
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		_ = os.Mkdir(dir, os.ModeDir)
 	}
 
-	file, err := os.OpenFile(logPath+filename+".log", os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	file, err := os.OpenFile(dir+filename+".log", os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
 	if err != nil {
 		log.Println(err)
 	}
@@ -6,6 +6,7 @@ import (
 )
 
 // A Queue is a simple thread safe FIFO queue based on a doubly linked list.
+// With Insert instead of Enqueue, it is a priority queue ordered by (lamport, name).
 type Queue struct {
 	list *list.List // list is the doubly linked list containing the elements of the Queue.
 	mu   sync.Mutex
@@ -24,6 +25,28 @@ func (q *Queue) Enqueue(lamport int32, name string) {
 	q.list.PushBack(&tuple{lamport: lamport, name: name})
 }
 
+// Insert creates and adds a tuple to the Queue before the first element it is ordered before by (lamport, name).
+func (q *Queue) Insert(lamport int32, name string) {
+	defer q.mu.Unlock()
+	q.mu.Lock()
+	for element := q.list.Front(); element != nil; element = element.Next() {
+		t := element.Value.(*tuple)
+		if Before(lamport, name, t.lamport, t.name) {
+			q.list.InsertBefore(&tuple{lamport: lamport, name: name}, element)
+			return
+		}
+	}
+	q.list.PushBack(&tuple{lamport: lamport, name: name})
+}
+
+// Front returns the first element of the Queue without removing it.
+func (q *Queue) Front() (int32, string) {
+	defer q.mu.Unlock()
+	q.mu.Lock()
+	element := q.list.Front()
+	return element.Value.(*tuple).lamport, element.Value.(*tuple).name
+}
+
 // Dequeue returns and removes the first element of the Queue.
 func (q *Queue) Dequeue() (int32, string) {
 	defer q.mu.Unlock()
@@ -33,6 +56,62 @@ func (q *Queue) Dequeue() (int32, string) {
 	return element.Value.(*tuple).lamport, element.Value.(*tuple).name
 }
 
+// Remove removes the first element of the Queue with the given name.
+// It reports whether such an element was found.
+func (q *Queue) Remove(name string) bool {
+	defer q.mu.Unlock()
+	q.mu.Lock()
+	for element := q.list.Front(); element != nil; element = element.Next() {
+		if element.Value.(*tuple).name == name {
+			q.list.Remove(element)
+			return true
+		}
+	}
+	return false
+}
+
+// Take removes the first element of the Queue with the given name and returns its lamport.
+// It reports whether such an element was found.
+func (q *Queue) Take(name string) (int32, bool) {
+	defer q.mu.Unlock()
+	q.mu.Lock()
+	for element := q.list.Front(); element != nil; element = element.Next() {
+		if t := element.Value.(*tuple); t.name == name {
+			q.list.Remove(element)
+			return t.lamport, true
+		}
+	}
+	return 0, false
+}
+
+// RemoveUpTo removes every element of the Queue with the given name and a lamport up to the given one.
+// It reports whether such an element was found.
+func (q *Queue) RemoveUpTo(lamport int32, name string) bool {
+	defer q.mu.Unlock()
+	q.mu.Lock()
+	found := false
+	for element := q.list.Front(); element != nil; {
+		next := element.Next()
+		if t := element.Value.(*tuple); t.name == name && t.lamport <= lamport {
+			q.list.Remove(element)
+			found = true
+		}
+		element = next
+	}
+	return found
+}
+
+// Names returns the names of all elements of the Queue, from front to back.
+func (q *Queue) Names() []string {
+	defer q.mu.Unlock()
+	q.mu.Lock()
+	names := make([]string, 0, q.list.Len())
+	for element := q.list.Front(); element != nil; element = element.Next() {
+		names = append(names, element.Value.(*tuple).name)
+	}
+	return names
+}
+
 // IsEmpty Is the Queue empty?
 func (q *Queue) IsEmpty() bool {
 	return q.list.Len() == 0
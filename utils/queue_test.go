@@ -0,0 +1,65 @@
+package utils
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// drain dequeues every element of q, and returns them as "lamport:name", from front to back.
+func drain(q *Queue) []string {
+	var elements []string
+	for !q.IsEmpty() {
+		lamport, name := q.Dequeue()
+		elements = append(elements, fmt.Sprintf("%v:%v", lamport, name))
+	}
+	return elements
+}
+
+// TestQueueInsert checks that Insert keeps the Queue ordered by lamport, with ties broken by name.
+func TestQueueInsert(t *testing.T) {
+	q := NewQueue()
+	q.Insert(3, "b")
+	q.Insert(1, "c")
+	q.Insert(3, "a")
+	q.Insert(2, "a")
+	q.Insert(1, "a")
+	q.Insert(4, "a")
+
+	want := []string{"1:a", "1:c", "2:a", "3:a", "3:b", "4:a"}
+	if got := drain(q); !reflect.DeepEqual(got, want) {
+		t.Errorf("the queue is %v, want %v", got, want)
+	}
+}
+
+// TestQueueRemoveUpTo checks that RemoveUpTo removes every element of a name up to a lamport, and only those.
+func TestQueueRemoveUpTo(t *testing.T) {
+	q := NewQueue()
+	q.Insert(1, "a")
+	q.Insert(2, "b")
+	q.Insert(3, "a")
+	q.Insert(4, "a")
+	q.Insert(5, "b")
+
+	if !q.RemoveUpTo(3, "a") {
+		t.Error("RemoveUpTo(3, a) found nothing to remove")
+	}
+	want := []string{"2:b", "4:a", "5:b"}
+	if got := q.Names(); !reflect.DeepEqual(got, []string{"b", "a", "b"}) {
+		t.Errorf("the names are %v after RemoveUpTo(3, a), want [b a b]", got)
+	}
+
+	if q.RemoveUpTo(3, "a") {
+		t.Error("RemoveUpTo(3, a) removed something a second time")
+	}
+	if q.RemoveUpTo(1, "b") {
+		t.Error("RemoveUpTo(1, b) removed a later request of b")
+	}
+	if q.RemoveUpTo(9, "c") {
+		t.Error("RemoveUpTo(9, c) removed an element of another name")
+	}
+
+	if got := drain(q); !reflect.DeepEqual(got, want) {
+		t.Errorf("the queue is %v, want %v", got, want)
+	}
+}